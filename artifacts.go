@@ -0,0 +1,594 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strings"
+	"time"
+)
+
+// zipWriter is a ZIP writer whose deflate compressor uses level, so that files can be compressed at different levels
+type zipWriter struct {
+	*zip.Writer
+	level int
+}
+
+// newZipWriter returns a ZIP writer that compresses files using opts.CompressionLevel, or the level chosen for each
+// file by opts.FileCompressionLevels
+func newZipWriter(w io.Writer, opts Options) *zipWriter {
+	zw := &zipWriter{Writer: zip.NewWriter(w), level: opts.CompressionLevel}
+	zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, zw.level)
+	})
+	return zw
+}
+
+// zipArchive is an archive that writes files into a ZIP file at the compression level selected by opts. Files with a
+// level of flate.NoCompression are stored without compression.
+type zipArchive struct {
+	zw   *zipWriter
+	opts Options
+}
+
+func (z *zipArchive) Create(name string) (io.Writer, error) {
+	z.zw.level = z.opts.compressionLevel(name)
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	if z.zw.level == flate.NoCompression {
+		header.Method = zip.Store
+	}
+	return z.zw.CreateHeader(header)
+}
+
+// writeArchive writes every artifact selected by opts into dst, without closing it
+func writeArchive(ctx context.Context, dst archive, opts Options) error {
+	if err := startFull(); err != nil {
+		return err
+	}
+
+	a := dst
+	manifest := &manifestWriter{archive: dst, timestamp: opts.now()}
+	if opts.IncludeManifest {
+		a = manifest
+	}
+	// The files written and snapshot collected so far are returned in a PartialError if a stage fails
+	files := &fileRecorder{archive: a}
+	a = files
+	var sn *Snapshot
+
+	// The heap dump is taken before snapshot.json so that a note can be recorded if it is skipped, but it is copied into
+	// the archive last. It is the only artifact that stops the world for more than an instant.
+	dump := &heapDump{}
+	defer dump.remove()
+	// Notes are recorded in snapshot.json, so anything that adds a note must come before it
+	notes := []string{}
+
+	stages := []struct {
+		err      error
+		progress string
+		include  bool
+		write    func() error
+	}{
+		{ErrCPUProfile, "cpu.pprof", opts.CPUProfileDuration > 0, func() error { return writeCPUProfile(ctx, a, opts) }},
+		{ErrTrace, "trace.out", opts.TraceDuration > 0, func() error { return writeTrace(ctx, a, opts) }},
+		{ErrHeapDump, "heap.bin-start", opts.IncludeHeapDump, func() error { return dump.take(opts) }},
+		{ErrExtraFiles, "extra files", len(opts.ExtraFiles) > 0, func() error {
+			skipped, err := writeExtraFiles(a, opts)
+			notes = append(notes, skipped...)
+			return err
+		}},
+		{ErrSnapshotJSON, "snapshot.json", opts.IncludeSnapshotJSON, func() error {
+			collected, err := writeSnapshotJSON(a, opts, append(dump.notes(), notes...))
+			sn = &collected
+			SetLast(collected)
+			return err
+		}},
+		{ErrSummary, "summary.json", opts.IncludeSummary, func() error {
+			if sn == nil {
+				collected := CollectWithOptions(opts.collectOptions())
+				sn = &collected
+			}
+			return writeSummaryJSON(a, opts, sn.Summary())
+		}},
+		{ErrStack, "stack.txt", opts.IncludeStack, func() error { return writeStack(a, opts) }},
+		{ErrCallerStack, "caller-stack.txt", opts.IncludeCallerStack, func() error {
+			stackFile, err := a.Create(path.Join(opts.Prefix, "caller-stack.txt"))
+			if err != nil {
+				return err
+			}
+			_, err = stackFile.Write(debug.Stack())
+			return err
+		}},
+		{ErrSched, "sched.txt", opts.IncludeSched, func() error {
+			schedFile, err := a.Create(path.Join(opts.Prefix, "sched.txt"))
+			if err != nil {
+				return err
+			}
+			return writeSched(schedFile)
+		}},
+		{ErrBlockProfile, "block.pprof", opts.IncludeBlockProfile, func() error { return writeProfile(a, opts.Prefix, "block") }},
+		{ErrMutexProfile, "mutex.pprof", opts.IncludeMutexProfile, func() error { return writeProfile(a, opts.Prefix, "mutex") }},
+		{ErrHeapProfile, "heap.pprof", opts.IncludeHeapProfile, func() error { return writeProfile(a, opts.Prefix, "heap") }},
+		{ErrProfile, "profiles", len(opts.IncludeProfiles) > 0, func() error { return writeProfiles(a, opts) }},
+		{ErrHeapDump, "heap.bin", opts.IncludeHeapDump, func() error {
+			write := dump.write
+			if opts.separateHeapDump != nil {
+				write = dump.writeSeparate
+			}
+			if err := write(ctx, a, opts); err != nil {
+				return err
+			}
+			opts.progress("heap.bin-done")
+			return nil
+		}},
+		// The manifest must be last, as it includes the hashes of every other file
+		{ErrManifest, "manifest.json", opts.IncludeManifest, func() error { return manifest.write(opts) }},
+	}
+	for _, stage := range stages {
+		if !stage.include {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		opts.progress(stage.progress)
+		written := len(files.names)
+		if err := stage.write(); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return &PartialError{Snapshot: sn, Artifacts: files.names[:written], Err: stageErr(stage.err, err)}
+		}
+	}
+	return nil
+}
+
+// fileRecorder is an archive that records the name of every file created in it
+type fileRecorder struct {
+	archive archive
+	names   []string
+}
+
+func (f *fileRecorder) Create(name string) (io.Writer, error) {
+	w, err := f.archive.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	f.names = append(f.names, name)
+	return w, nil
+}
+
+func (f *fileRecorder) CreateSized(name string, size int64) (io.Writer, error) {
+	w, err := createSized(f.archive, name, size)
+	if err != nil {
+		return nil, err
+	}
+	f.names = append(f.names, name)
+	return w, nil
+}
+
+// writeCPUProfile samples a CPU profile for opts.CPUProfileDuration, or until ctx is cancelled. This does not pause
+// execution.
+func writeCPUProfile(ctx context.Context, a archive, opts Options) error {
+	profileFile, err := a.Create(path.Join(opts.Prefix, "cpu.pprof"))
+	if err != nil {
+		return err
+	}
+	if err := pprof.StartCPUProfile(profileFile); err != nil {
+		return err
+	}
+	defer pprof.StopCPUProfile()
+
+	timer := time.NewTimer(opts.CPUProfileDuration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeTrace records an execution trace for opts.TraceDuration, or until ctx is cancelled. The trace is written to a
+// temporary file while it is being recorded and then copied into the archive. This does not pause execution.
+func writeTrace(ctx context.Context, a archive, opts Options) error {
+	tmpFile, err := os.CreateTemp(opts.TempDir, "trace")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	if err := trace.Start(tmpFile); err != nil {
+		return err
+	}
+	timer := time.NewTimer(opts.TraceDuration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	trace.Stop()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	size, err := tmpFile.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	traceFile, err := createSized(a, path.Join(opts.Prefix, "trace.out"), size)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(traceFile, &contextReader{ctx: ctx, r: tmpFile})
+	return err
+}
+
+// writeExtraFiles copies each file in opts.ExtraFiles into the archive. Files that can't be opened or aren't regular
+// files are skipped, and files that can't be read to the end are left incomplete. A note is returned for each of them.
+func writeExtraFiles(a archive, opts Options) ([]string, error) {
+	names := make([]string, 0, len(opts.ExtraFiles))
+	for name := range opts.ExtraFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	notes := []string{}
+	for _, name := range names {
+		note, err := copyExtraFile(a, name, opts)
+		if note != "" {
+			notes = append(notes, note)
+		}
+		if err != nil {
+			return notes, err
+		}
+	}
+	return notes, nil
+}
+
+// copyExtraFile copies the extra file name into the archive, returning a note if it was skipped or is incomplete. Only
+// errors writing to the archive are returned.
+func copyExtraFile(a archive, name string, opts Options) (string, error) {
+	fileName := opts.ExtraFiles[name]
+	if _, err := statExtraFile(fileName); err != nil {
+		return fmt.Sprintf("%s skipped: %s", name, err.Error()), nil
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Sprintf("%s skipped: %s", name, err.Error()), nil
+	}
+	defer f.Close()
+
+	w, err := a.Create(path.Join(opts.Prefix, name))
+	if err != nil {
+		return "", err
+	}
+	r := &readErrorRecorder{r: f}
+	if _, err := io.Copy(w, r); err != nil {
+		if r.err != nil {
+			return fmt.Sprintf("%s is incomplete: %s", name, r.err.Error()), nil
+		}
+		return "", err
+	}
+	return "", nil
+}
+
+// statExtraFile returns information about an extra file, or an error if it isn't a regular file. Directories can't be
+// copied, and reading a FIFO or a device such as /dev/zero may block or never end.
+func statExtraFile(fileName string) (os.FileInfo, error) {
+	info, err := os.Stat(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", fileName)
+	}
+	return info, nil
+}
+
+// readErrorRecorder is a reader that records the error of the underlying reader, so that it can be told apart from an
+// error writing what was read
+type readErrorRecorder struct {
+	r   io.Reader
+	err error
+}
+
+func (r *readErrorRecorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// writeSnapshotJSON writes snapshot.json. Collecting the snapshot pauses execution briefly to read memory statistics
+// and the stacks of all goroutines.
+func writeSnapshotJSON(a archive, opts Options, notes []string) (Snapshot, error) {
+	sn := CollectWithOptions(opts.collectOptions())
+	sn.Notes = notes
+	sn.PanicValue = opts.panicValue
+	sn.PanicStack = opts.panicStack
+
+	snapshotFile, err := a.Create(path.Join(opts.Prefix, "snapshot.json"))
+	if err != nil {
+		return sn, err
+	}
+	return sn, sn.WriteJSONIndent(snapshotFile, opts.Indent)
+}
+
+// writeSummaryJSON writes summary.json
+func writeSummaryJSON(a archive, opts Options, summary Summary) error {
+	summaryFile, err := a.Create(path.Join(opts.Prefix, "summary.json"))
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(summaryFile)
+	encoder.SetIndent("", opts.Indent)
+	return encoder.Encode(summary)
+}
+
+// writeStack writes stack.txt. The goroutine profile pauses execution briefly at the start and end of collection.
+func writeStack(a archive, opts Options) error {
+	traceFile, err := a.Create(path.Join(opts.Prefix, "stack.txt"))
+	if err != nil {
+		return err
+	}
+	// Level 0 is the binary pprof format, not text
+	level := opts.StackDebugLevel
+	if level == 0 {
+		level = 1
+	}
+	if opts.MaxStackBytes <= 0 {
+		return pprof.Lookup("goroutine").WriteTo(traceFile, level)
+	}
+
+	limited := &limitWriter{w: traceFile, remaining: opts.MaxStackBytes}
+	if err := pprof.Lookup("goroutine").WriteTo(limited, level); err != nil {
+		return err
+	}
+	if limited.truncated {
+		_, err := fmt.Fprintf(traceFile, "\n[truncated at %d bytes, %d goroutines]\n", opts.MaxStackBytes, runtime.NumGoroutine())
+		return err
+	}
+	return nil
+}
+
+// limitWriter writes at most remaining bytes to w and discards the rest
+type limitWriter struct {
+	w         io.Writer
+	remaining int64
+	truncated bool
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+		l.truncated = true
+	}
+	if len(p) > 0 {
+		if _, err := l.w.Write(p); err != nil {
+			return 0, err
+		}
+		l.remaining -= int64(len(p))
+	}
+	return n, nil
+}
+
+// writeProfile writes the named pprof profile. This does not pause execution.
+func writeProfile(a archive, prefix string, name string) error {
+	profileFile, err := a.Create(path.Join(prefix, name+".pprof"))
+	if err != nil {
+		return err
+	}
+	return pprof.Lookup(name).WriteTo(profileFile, 0)
+}
+
+// writeProfiles writes each of opts.IncludeProfiles that was not already written by one of the other options
+func writeProfiles(a archive, opts Options) error {
+	written := map[string]bool{
+		"block": opts.IncludeBlockProfile,
+		"mutex": opts.IncludeMutexProfile,
+		"heap":  opts.IncludeHeapProfile,
+	}
+	for _, name := range opts.IncludeProfiles {
+		if written[name] {
+			continue
+		}
+		written[name] = true
+		if pprof.Lookup(name) == nil {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+		if err := writeProfile(a, opts.Prefix, name); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// heapDump is a heap dump written to a temporary file, which is later copied into the archive as heap.bin
+type heapDump struct {
+	f    *os.File
+	note string
+}
+
+// take writes the heap dump to a temporary file. This stops the world for the entire duration of the dump, which must
+// be written to a file, so it is copied into the archive after execution resumes. If the dump could be larger than
+// opts.MaxHeapDumpBytes it is not taken at all, see heapDumpTooLarge, and if it turns out larger it is discarded.
+func (d *heapDump) take(opts Options) error {
+	if opts.MaxHeapDumpBytes > 0 {
+		if note := heapDumpTooLarge(opts); note != "" {
+			d.note = note
+			return nil
+		}
+	}
+
+	tmpFile, err := os.CreateTemp(opts.TempDir, "dump")
+	if err != nil {
+		return err
+	}
+	d.f = tmpFile
+
+	debug.WriteHeapDump(tmpFile.Fd())
+	info, err := tmpFile.Stat()
+	if err != nil {
+		return err
+	}
+	if opts.MaxHeapDumpBytes > 0 && info.Size() > opts.MaxHeapDumpBytes {
+		d.note = fmt.Sprintf("heap.bin skipped: heap dump of %d bytes exceeds the limit of %d bytes", info.Size(), opts.MaxHeapDumpBytes)
+		d.remove()
+		return nil
+	}
+	if opts.separateHeapDump != nil {
+		d.note = fmt.Sprintf("heap.bin written to %s next to the archive", filepath.Base(opts.separateHeapDump.fileName))
+	}
+	_, err = tmpFile.Seek(0, io.SeekStart)
+	return err
+}
+
+// heapDumpTooLarge returns a note explaining why the heap dump is skipped if it could be larger than
+// opts.MaxHeapDumpBytes, or than the space available for the temporary file, or an empty string if it can be taken.
+// The largest the dump can be is the memory obtained from the OS by the go runtime.
+func heapDumpTooLarge(opts Options) string {
+	upperBound := runtimeMemory()
+	if upperBound > opts.MaxHeapDumpBytes {
+		return fmt.Sprintf("heap.bin skipped: heap dump could be up to %d bytes, which exceeds the limit of %d bytes", upperBound, opts.MaxHeapDumpBytes)
+	}
+	dir := opts.TempDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	// Total is zero where disk usage is not supported
+	if disk, err := diskUsage(dir); err == nil && disk.Total > 0 && uint64(upperBound) > disk.Available {
+		return fmt.Sprintf("heap.bin skipped: heap dump could be up to %d bytes, but only %d bytes are available in %s", upperBound, disk.Available, dir)
+	}
+	return ""
+}
+
+// notes returns notes about the heap dump to record in snapshot.json
+func (d *heapDump) notes() []string {
+	if d.note == "" {
+		return nil
+	}
+	return []string{d.note}
+}
+
+// write copies the heap dump into the archive as heap.bin, unless it was skipped. Only the copy can be interrupted by
+// cancelling ctx.
+func (d *heapDump) write(ctx context.Context, a archive, opts Options) error {
+	if d.f == nil {
+		return nil
+	}
+
+	info, err := d.f.Stat()
+	if err != nil {
+		return err
+	}
+	dumpFile, err := createSized(a, path.Join(opts.Prefix, "heap.bin"), info.Size())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dumpFile, &contextReader{ctx: ctx, r: d.f})
+	return err
+}
+
+// writeSeparate writes the heap dump to the file chosen by opts.SeparateHeapDump instead of the archive, unless it was
+// skipped, and records its size and hash for the manifest
+func (d *heapDump) writeSeparate(ctx context.Context, _ archive, opts Options) error {
+	if d.f == nil {
+		return nil
+	}
+
+	separate := opts.separateHeapDump
+	err := writeFileAtomic(separate.fileName, func(w io.Writer) error {
+		hw := &hashingWriter{w: w, hash: sha256.New()}
+		if _, err := io.Copy(hw, &contextReader{ctx: ctx, r: d.f}); err != nil {
+			return err
+		}
+		separate.file = ManifestFile{
+			Name:   filepath.Base(separate.fileName),
+			Size:   hw.size,
+			SHA256: hex.EncodeToString(hw.hash.Sum(nil)),
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	separate.written = true
+	return nil
+}
+
+// separateHeapDump is the file next to the archive that the heap dump is written to when Options.SeparateHeapDump is
+// set
+type separateHeapDump struct {
+	fileName string
+	written  bool
+	file     ManifestFile
+}
+
+// withSeparateHeapDump calls write with opts, set up to write the heap dump next to fileName if opts.SeparateHeapDump
+// is set. That file is removed again if write fails, so that it doesn't outlive the archive it belongs to.
+func withSeparateHeapDump(fileName string, opts Options, write func(opts Options) error) error {
+	if !opts.SeparateHeapDump || !opts.IncludeHeapDump {
+		return write(opts)
+	}
+
+	opts.separateHeapDump = &separateHeapDump{fileName: heapDumpFileName(fileName)}
+	err := write(opts)
+	if err != nil && opts.separateHeapDump.written {
+		os.Remove(opts.separateHeapDump.fileName)
+	}
+	return err
+}
+
+// heapDumpFileName returns the name of the file next to the archive fileName that the heap dump is written to, such as
+// "snapshot.heap.bin" for "snapshot.zip"
+func heapDumpFileName(fileName string) string {
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(fileName, ext) {
+			return strings.TrimSuffix(fileName, ext) + ".heap.bin"
+		}
+	}
+	return fileName + ".heap.bin"
+}
+
+// remove closes and removes the temporary file, if any
+func (d *heapDump) remove() {
+	if d.f == nil {
+		return
+	}
+	d.f.Close()
+	os.Remove(d.f.Name())
+	d.f = nil
+}
+
+// contextReader is a reader that fails once ctx is cancelled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,132 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtraFiles(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"debug":true}`), 0644); err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+
+	opts := DefaultOptions()
+	opts.IncludeHeapDump = false
+	opts.ExtraFiles = map[string]string{
+		"config.json":  configFile,
+		"dir":          dir,
+		"missing.json": filepath.Join(dir, "missing.json"),
+	}
+	skipped := []string{"dir", "missing.json"}
+	if _, err := os.Stat("/dev/zero"); err == nil {
+		opts.ExtraFiles["zero"] = "/dev/zero"
+		skipped = append(skipped, "zero")
+	}
+	// Reading the memory of the process at address 0 fails
+	incomplete := []string{}
+	if _, err := os.Stat("/proc/self/mem"); err == nil {
+		opts.ExtraFiles["mem"] = "/proc/self/mem"
+		incomplete = append(incomplete, "mem")
+	}
+	fileName := filepath.Join(dir, "debug.zip")
+	if err := FullWithOptions(fileName, opts); err != nil {
+		t.Fatalf("Error taking snapshot: %s", err.Error())
+	}
+
+	zr, err := zip.OpenReader(fileName)
+	if err != nil {
+		t.Fatalf("Error opening snapshot: %s", err.Error())
+	}
+	defer zr.Close()
+	config := findArchiveFile(&zr.Reader, "config.json")
+	if config == nil {
+		t.Fatalf("config.json not found in snapshot")
+	}
+	r, err := config.Open()
+	if err != nil {
+		t.Fatalf("Error opening config.json: %s", err.Error())
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil || string(data) != `{"debug":true}` {
+		t.Errorf("Unexpected contents of config.json: %q %v", data, err)
+	}
+
+	sn, err := LoadFile(fileName)
+	if err != nil {
+		t.Fatalf("Error loading snapshot: %s", err.Error())
+	}
+	for _, name := range incomplete {
+		if !containsNote(sn.Notes, name+" is incomplete: ") {
+			t.Errorf("Expected a note that %s is incomplete, got %v", name, sn.Notes)
+		}
+	}
+	for _, name := range skipped {
+		if findArchiveFile(&zr.Reader, name) != nil {
+			t.Errorf("Expected %s to be left out of the snapshot", name)
+		}
+		if !containsNote(sn.Notes, name+" skipped: ") {
+			t.Errorf("Expected a note that %s was skipped, got %v", name, sn.Notes)
+		}
+	}
+}
+
+func containsNote(notes []string, prefix string) bool {
+	for _, note := range notes {
+		if strings.HasPrefix(note, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readArchiveJSON decodes the file name in zr into v
+func readArchiveJSON(t *testing.T, zr *zip.Reader, name string, v interface{}) {
+	file := findArchiveFile(zr, name)
+	if file == nil {
+		t.Fatalf("%s not found in snapshot", name)
+	}
+	r, err := file.Open()
+	if err != nil {
+		t.Fatalf("Error opening %s: %s", name, err.Error())
+	}
+	defer r.Close()
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		t.Fatalf("Error decoding %s: %s", name, err.Error())
+	}
+}
+
+func TestSummaryWithoutSnapshotJSON(t *testing.T) {
+	fixed := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	opts := DefaultOptions()
+	opts.IncludeHeapDump = false
+	opts.IncludeSnapshotJSON = false
+	opts.IncludeSummary = true
+	opts.IncludeManifest = true
+	opts.Now = func() time.Time { return fixed }
+	buf := &bytes.Buffer{}
+	if err := FullToWithOptions(buf, opts); err != nil {
+		t.Fatalf("Error taking snapshot: %s", err.Error())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Error reading snapshot: %s", err.Error())
+	}
+
+	summary := Summary{}
+	readArchiveJSON(t, zr, "summary.json", &summary)
+	manifest := Manifest{}
+	readArchiveJSON(t, zr, "manifest.json", &manifest)
+	if !summary.Timestamp.Equal(fixed) || !manifest.Timestamp.Equal(fixed) {
+		t.Errorf("Expected the timestamps of summary.json and manifest.json to be %s, got %s and %s", fixed, summary.Timestamp, manifest.Timestamp)
+	}
+}
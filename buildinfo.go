@@ -0,0 +1,19 @@
+package snapshot
+
+import "runtime/debug"
+
+// readBuildSettings populates the fields of s that are derived from the build settings in info
+func readBuildSettings(s *Snapshot, info *debug.BuildInfo) {
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			s.VCSRevision = setting.Value
+		case "vcs.time":
+			s.VCSTime = setting.Value
+		case "vcs.modified":
+			s.VCSModified = setting.Value == "true"
+		case "CGO_ENABLED":
+			s.CgoEnabled = setting.Value == "1"
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package snapshot
+
+import (
+	"container/list"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Cache keeps the most recent snapshot for each of a number of reasons in memory, such as "panic", "health-check" or
+// "signal", as a lightweight alternative to writing each snapshot to a file. When more reasons than the maximum are
+// stored, the reason that was least recently stored or retrieved is evicted. A Cache is safe for concurrent use.
+type Cache struct {
+	max     int
+	order   *list.List
+	entries map[string]*list.Element
+	lock    sync.Mutex
+}
+
+type cacheEntry struct {
+	reason   string
+	snapshot Snapshot
+}
+
+// NewCache will create a new cache that keeps the snapshots of at most maxReasons reasons
+func NewCache(maxReasons int) *Cache {
+	if maxReasons < 1 {
+		maxReasons = 1
+	}
+	return &Cache{
+		max:     maxReasons,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// Store will store s as the most recent snapshot for reason, replacing any snapshot previously stored for it
+func (c *Cache) Store(reason string, s Snapshot) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if element, ok := c.entries[reason]; ok {
+		element.Value.(*cacheEntry).snapshot = s
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[reason] = c.order.PushFront(&cacheEntry{reason: reason, snapshot: s})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).reason)
+	}
+}
+
+// Get returns the most recent snapshot stored for reason, and false if there is none
+func (c *Cache) Get(reason string) (Snapshot, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.entries[reason]
+	if !ok {
+		return Snapshot{}, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).snapshot, true
+}
+
+// Reasons returns the reasons that have a snapshot stored, most recently used first
+func (c *Cache) Reasons() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	reasons := make([]string, 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		reasons = append(reasons, element.Value.(*cacheEntry).reason)
+	}
+	return reasons
+}
+
+// Handler returns an HTTP handler that responds with the snapshot stored for the reason in the "reason" query
+// parameter as JSON, in the same format as snapshot.json, or 404 Not Found if there is none. Without a reason, it
+// responds with a JSON object of the timestamp of the snapshot stored for each reason.
+//
+// Warning: snapshots contain the environment of your application, which may include secrets. The handler does no
+// authentication of its own, you must protect the route it is registered on.
+func (c *Cache) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		reason := r.URL.Query().Get("reason")
+		if reason == "" {
+			timestamps := map[string]time.Time{}
+			c.lock.Lock()
+			for reason, element := range c.entries {
+				timestamps[reason] = element.Value.(*cacheEntry).snapshot.Timestamp
+			}
+			c.lock.Unlock()
+			json.NewEncoder(w).Encode(timestamps)
+			return
+		}
+
+		s, ok := c.Get(reason)
+		if !ok {
+			http.Error(w, "no snapshot for reason", http.StatusNotFound)
+			return
+		}
+		s.WriteJSON(w)
+	})
+}
@@ -0,0 +1,55 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// FullToConn will take a full detailed snapshot of your go application, like Full, and send the ZIP file over conn,
+// prefixed with its length in bytes as a big endian uint64. This lets a receiver on the other end of a connection that
+// stays open know when the snapshot is complete, see ReadFromConn. conn is not closed.
+//
+// The length must be known before anything is sent, so the snapshot is taken in memory with FullBytes first. See
+// FullBytes for how much memory that uses.
+//
+// Warning: this will temporarily suspend all execution of your application while the heap dump is written.
+func FullToConn(conn net.Conn) error {
+	data, err := FullBytes()
+	if err != nil {
+		return err
+	}
+
+	prefix := binary.BigEndian.AppendUint64(nil, uint64(len(data)))
+	if _, err := conn.Write(prefix); err != nil {
+		return err
+	}
+	_, err = conn.Write(data)
+	return err
+}
+
+// ReadFromConn reads a snapshot ZIP file sent by FullToConn from r. If maxBytes is greater than zero, an error is
+// returned without reading the snapshot if it is larger than maxBytes, which protects a collector that accepts snapshots
+// from many processes from running out of memory.
+func ReadFromConn(r io.Reader, maxBytes int64) ([]byte, error) {
+	prefix := make([]byte, 8)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint64(prefix)
+	if maxBytes > 0 && length > uint64(maxBytes) {
+		return nil, fmt.Errorf("snapshot of %d bytes exceeds the limit of %d bytes", length, maxBytes)
+	}
+
+	// The length is not trusted to allocate the buffer up front, a truncated stream only uses as much as was sent
+	buf := &bytes.Buffer{}
+	if _, err := io.CopyN(buf, r, int64(length)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
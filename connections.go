@@ -0,0 +1,15 @@
+package snapshot
+
+// Connection describes a network socket open in the process
+type Connection struct {
+	// Protocol is one of "tcp", "tcp6", "udp", or "udp6"
+	Protocol string `json:"protocol"`
+	// LocalAddress is the local address and port of the socket
+	LocalAddress string `json:"local_address"`
+	// RemoteAddress is the remote address and port of the socket, which is unspecified for listening sockets
+	RemoteAddress string `json:"remote_address"`
+	// State is the state of the socket, such as "ESTABLISHED" or "LISTEN"
+	State string `json:"state"`
+	// FD is the file descriptor of the socket
+	FD int `json:"fd"`
+}
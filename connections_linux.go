@@ -0,0 +1,127 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+	"0C": "NEW_SYN_RECV",
+}
+
+// connections returns the TCP and UDP sockets among files, using the socket tables in /proc/net
+func connections(files []OpenFile) ([]Connection, error) {
+	return readConnections("/proc/self", files)
+}
+
+// readConnections returns the TCP and UDP sockets among files of the process whose /proc directory is procDir, which
+// may be in a different network namespace
+func readConnections(procDir string, files []OpenFile) ([]Connection, error) {
+	socketFDs := map[string]int{}
+	for _, file := range files {
+		if strings.HasPrefix(file.Target, "socket:[") && strings.HasSuffix(file.Target, "]") {
+			socketFDs[file.Target[len("socket:["):len(file.Target)-1]] = file.FD
+		}
+	}
+	if len(socketFDs) == 0 {
+		return nil, nil
+	}
+
+	conns := []Connection{}
+	for _, protocol := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		c, err := readSocketTable(procDir+"/net/"+protocol, protocol, socketFDs)
+		if err != nil {
+			// IPv6 may be disabled, in which case its tables don't exist
+			if os.IsNotExist(err) {
+				continue
+			}
+			return conns, err
+		}
+		conns = append(conns, c...)
+	}
+	return conns, nil
+}
+
+func readSocketTable(fileName string, protocol string, socketFDs map[string]int) ([]Connection, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	conns := []Connection{}
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Skip the header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+		fd, ok := socketFDs[fields[9]]
+		if !ok {
+			continue
+		}
+
+		local, err := parseSocketAddress(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		remote, err := parseSocketAddress(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		state := tcpStates[fields[3]]
+		if strings.HasPrefix(protocol, "udp") {
+			// UDP sockets only use the established and close states, close meaning not connected
+			if fields[3] == "07" {
+				state = "UNCONNECTED"
+			}
+		}
+
+		conns = append(conns, Connection{
+			Protocol:      protocol,
+			LocalAddress:  local,
+			RemoteAddress: remote,
+			State:         state,
+			FD:            fd,
+		})
+	}
+	return conns, scanner.Err()
+}
+
+// parseSocketAddress parses an address like "0100007F:1F90". The address is made up of 32-bit words in host
+// byte order, which is little endian on all architectures this is likely to run on, and the port is big endian.
+func parseSocketAddress(s string) (string, error) {
+	addr, portHex, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", fmt.Errorf("invalid socket address %q", s)
+	}
+	ip, err := hex.DecodeString(addr)
+	if err != nil || (len(ip) != net.IPv4len && len(ip) != net.IPv6len) {
+		return "", fmt.Errorf("invalid socket address %q", s)
+	}
+	for i := 0; i < len(ip); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = ip[i+3], ip[i+2], ip[i+1], ip[i]
+	}
+	port, err := strconv.ParseUint(portHex, 16, 16)
+	if err != nil {
+		return "", fmt.Errorf("invalid socket address %q", s)
+	}
+	return net.JoinHostPort(net.IP(ip).String(), strconv.FormatUint(port, 10)), nil
+}
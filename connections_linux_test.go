@@ -0,0 +1,78 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSocketAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		expected string
+		invalid  bool
+	}{
+		// From /proc/net/tcp
+		{"IPv4 loopback", "0100007F:BC8F", "127.0.0.1:48271", false},
+		{"IPv4 any", "00000000:07E8", "0.0.0.0:2024", false},
+		{"IPv4", "0A01A8C0:0050", "192.168.1.10:80", false},
+		// From /proc/net/tcp6
+		{"IPv6 any", "00000000000000000000000000000000:A8F5", "[::]:43253", false},
+		{"IPv6 loopback", "00000000000000000000000001000000:8F67", "[::1]:36711", false},
+		{"IPv6", "B80D0120000000000000000001000000:01BB", "[2001:db8::1]:443", false},
+		{"IPv6 link local", "000080FE00000000FF005002B1E4C8FE:0016", "[fe80::250:ff:fec8:e4b1]:22", false},
+		// IPv4 connections to an IPv6 socket, which are shown as IPv4 addresses
+		{"IPv4 mapped", "0000000000000000FFFF00000100007F:A8F5", "127.0.0.1:43253", false},
+		{"IPv4 mapped remote", "0000000000000000FFFF00000A01A8C0:85A6", "192.168.1.10:34214", false},
+		{"no port", "0100007F", "", true},
+		{"invalid port", "0100007F:XYZ", "", true},
+		{"port too large", "0100007F:10000", "", true},
+		{"invalid address", "0100007G:0050", "", true},
+		{"wrong length", "01000070F:0050", "", true},
+		{"truncated IPv6", "0000000000000000FFFF0000:0050", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			address, err := parseSocketAddress(test.address)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("Expected an error, got %s", address)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error parsing address: %s", err.Error())
+			}
+			if address != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, address)
+			}
+		})
+	}
+}
+
+func TestReadSocketTable(t *testing.T) {
+	tcp6 := `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:A8F5 00000000000000000000000000000000:0000 0A 00000000:00000001 00:00000000 00000000     0        0 278525 2 00000000490d142a 100 0 0 10 0
+   1: 00000000000000000000000001000000:8F67 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 278527 1 000000005c557d49 100 0 0 10 0
+   2: 0000000000000000FFFF00000100007F:A8F5 0000000000000000FFFF00000100007F:85A6 01 00000000:00000000 00:00000000 00000000     0        0 278530 1 00000000182f2379 20 0 0 10 -1
+`
+	fileName := filepath.Join(t.TempDir(), "tcp6")
+	if err := os.WriteFile(fileName, []byte(tcp6), 0644); err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+
+	// Sockets that aren't open in this process are left out
+	conns, err := readSocketTable(fileName, "tcp6", map[string]int{"278525": 3, "278530": 7})
+	if err != nil {
+		t.Fatalf("Error reading socket table: %s", err.Error())
+	}
+	expected := []Connection{
+		{Protocol: "tcp6", LocalAddress: "[::]:43253", RemoteAddress: "[::]:0", State: "LISTEN", FD: 3},
+		{Protocol: "tcp6", LocalAddress: "127.0.0.1:43253", RemoteAddress: "127.0.0.1:34214", State: "ESTABLISHED", FD: 7},
+	}
+	if !reflect.DeepEqual(conns, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, conns)
+	}
+}
@@ -0,0 +1,24 @@
+package snapshot
+
+// ContainerInfo describes the container the process is running in, if any. Detection is best-effort and only supported
+// on Linux.
+type ContainerInfo struct {
+	// Detected is true if the process appears to be running in a container
+	Detected bool `json:"detected"`
+	// Runtime is the container runtime or orchestrator that was detected, such as "docker", "kubernetes", "podman",
+	// "containerd", or "lxc". It may be empty even if a container was detected.
+	Runtime string `json:"runtime,omitempty"`
+	// CgroupVersion is the version of cgroups in use, 1 or 2, or 0 if unknown
+	CgroupVersion int `json:"cgroup_version,omitempty"`
+	// MemoryLimitBytes is the memory limit of the cgroup the process is in, or 0 if there is no limit
+	MemoryLimitBytes int64 `json:"memory_limit_bytes"`
+	// MemoryUsageBytes is the memory currently used by the cgroup the process is in, including memory not used by the
+	// go runtime such as the page cache
+	MemoryUsageBytes int64 `json:"memory_usage_bytes"`
+	// CPUQuota is the number of CPUs worth of time the cgroup the process is in may use, or 0 if there is no limit
+	CPUQuota float64 `json:"cpu_quota"`
+	// MemoryStat are the counters in the memory.stat file of the cgroup the process is in, such as "anon", "file" and
+	// "pgmajfault". The counters differ between cgroup versions, see the kernel documentation of cgroups. A high rate of
+	// major page faults means the kernel is evicting and reading back pages because memory is short.
+	MemoryStat map[string]uint64 `json:"memory_stat,omitempty"`
+}
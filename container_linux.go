@@ -0,0 +1,169 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupV1Unlimited is the smallest value reported by cgroup v1 as a memory limit that means there is no limit. The
+// exact value depends on the page size.
+const cgroupV1Unlimited = 1 << 62
+
+func containerInfo() ContainerInfo {
+	info := ContainerInfo{}
+
+	cgroups, _ := os.ReadFile("/proc/self/cgroup")
+	for _, runtime := range []string{"kubepods", "docker", "libpod", "containerd", "lxc"} {
+		if strings.Contains(string(cgroups), runtime) {
+			info.Detected = true
+			info.Runtime = runtime
+			break
+		}
+	}
+	if info.Runtime == "kubepods" || (info.Runtime == "" && os.Getenv("KUBERNETES_SERVICE_HOST") != "") {
+		info.Runtime = "kubernetes"
+		info.Detected = true
+	} else if info.Runtime == "libpod" {
+		info.Runtime = "podman"
+	}
+	if !info.Detected {
+		if _, err := os.Stat("/.dockerenv"); err == nil {
+			info.Detected = true
+			info.Runtime = "docker"
+		} else if _, err := os.Stat("/run/.containerenv"); err == nil {
+			info.Detected = true
+			info.Runtime = "podman"
+		}
+	}
+
+	readCgroups(&info, "/sys/fs/cgroup", string(cgroups))
+	return info
+}
+
+// readCgroups reads the version and limits of the cgroups of the process from the cgroup file system mounted at root,
+// given the contents of /proc/self/cgroup
+func readCgroups(info *ContainerInfo, root, cgroups string) {
+	// The root cgroup is only where the cgroup of the process is mounted in a container, otherwise its files describe
+	// the entire host
+	fallback := info.Detected
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		info.CgroupVersion = 2
+		readCgroupV2Limits(info, cgroupDir{root: root, path: cgroupPaths(cgroups)[""], fallback: fallback})
+	} else if len(cgroups) > 0 {
+		info.CgroupVersion = 1
+		paths := cgroupPaths(cgroups)
+		memory := cgroupDir{root: filepath.Join(root, "memory"), path: paths["memory"], fallback: fallback}
+		cpu := cgroupDir{root: filepath.Join(root, "cpu"), path: paths["cpu"], fallback: fallback}
+		readCgroupV1Limits(info, memory, cpu)
+	}
+}
+
+// cgroupPaths parses /proc/self/cgroup and returns the path of the cgroup for each controller. The cgroup v2 path has
+// an empty controller name.
+func cgroupPaths(cgroups string) map[string]string {
+	paths := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(cgroups), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[1] == "" {
+			paths[""] = parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+	return paths
+}
+
+// cgroupDir is the directory of a cgroup at path within the cgroup file system mounted at root
+type cgroupDir struct {
+	root string
+	path string
+	// fallback is true if files that don't exist at path are read from root instead, which is where the cgroup of the
+	// process is mounted when it runs in a container with its own cgroup namespace, but /proc/self/cgroup shows the
+	// path on the host
+	fallback bool
+}
+
+// read reads the file name in the cgroup directory
+func (d cgroupDir) read(name string) (string, bool) {
+	dirs := []string{filepath.Join(d.root, d.path)}
+	if d.fallback {
+		dirs = append(dirs, d.root)
+	}
+	for _, dir := range dirs {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			return strings.TrimSpace(string(data)), true
+		}
+	}
+	return "", false
+}
+
+func readCgroupV2Limits(info *ContainerInfo, dir cgroupDir) {
+	if limit, ok := dir.read("memory.max"); ok && limit != "max" {
+		info.MemoryLimitBytes, _ = strconv.ParseInt(limit, 10, 64)
+	}
+	if usage, ok := dir.read("memory.current"); ok {
+		info.MemoryUsageBytes, _ = strconv.ParseInt(usage, 10, 64)
+	}
+	if stat, ok := dir.read("memory.stat"); ok {
+		info.MemoryStat = parseMemoryStat(stat)
+	}
+	if cpu, ok := dir.read("cpu.max"); ok {
+		quota, period, _ := strings.Cut(cpu, " ")
+		info.CPUQuota = cpuQuota(quota, period)
+	}
+}
+
+func readCgroupV1Limits(info *ContainerInfo, memory, cpu cgroupDir) {
+	if limit, ok := memory.read("memory.limit_in_bytes"); ok {
+		if n, err := strconv.ParseInt(limit, 10, 64); err == nil && n < cgroupV1Unlimited {
+			info.MemoryLimitBytes = n
+		}
+	}
+	if usage, ok := memory.read("memory.usage_in_bytes"); ok {
+		info.MemoryUsageBytes, _ = strconv.ParseInt(usage, 10, 64)
+	}
+	if stat, ok := memory.read("memory.stat"); ok {
+		info.MemoryStat = parseMemoryStat(stat)
+	}
+	quota, ok := cpu.read("cpu.cfs_quota_us")
+	if !ok {
+		return
+	}
+	period, _ := cpu.read("cpu.cfs_period_us")
+	info.CPUQuota = cpuQuota(quota, period)
+}
+
+// parseMemoryStat parses a memory.stat file, which has a counter on each line like "pgmajfault 123"
+func parseMemoryStat(stat string) map[string]uint64 {
+	counters := map[string]uint64{}
+	for _, line := range strings.Split(stat, "\n") {
+		name, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+			counters[name] = n
+		}
+	}
+	return counters
+}
+
+// cpuQuota returns the number of CPUs allowed by a quota and period in microseconds, or 0 if there is no quota
+func cpuQuota(quota, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
@@ -0,0 +1,273 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	cgroupV1TestFile = `12:pids:/docker/3f2a
+11:memory:/docker/3f2a
+10:cpu,cpuacct:/docker/3f2a
+9:name=systemd:/docker/3f2a
+1:blkio:/docker/3f2a
+`
+	cgroupV2TestFile = "0::/kubepods.slice/kubepods-burstable.slice/cri-containerd-9b1c.scope\n"
+	// memory.stat of cgroup v2, including a counter that isn't a number
+	memoryStatV2TestFile = `anon 2486272
+file 1458176
+kernel 450560
+pgfault 4189
+pgmajfault 3
+invalid x
+`
+	memoryStatV1TestFile = `cache 1458176
+rss 2486272
+pgmajfault 7
+hierarchical_memory_limit 9223372036854771712
+total_rss 2486272
+`
+)
+
+func TestCgroupPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		cgroups  string
+		expected map[string]string
+	}{
+		{"version 1", cgroupV1TestFile, map[string]string{
+			"pids":         "/docker/3f2a",
+			"memory":       "/docker/3f2a",
+			"cpu":          "/docker/3f2a",
+			"cpuacct":      "/docker/3f2a",
+			"name=systemd": "/docker/3f2a",
+			"blkio":        "/docker/3f2a",
+		}},
+		{"version 2", cgroupV2TestFile, map[string]string{
+			"": "/kubepods.slice/kubepods-burstable.slice/cri-containerd-9b1c.scope",
+		}},
+		{"version 2 namespace", "0::/\n", map[string]string{"": "/"}},
+		{"hybrid", "1:name=systemd:/user.slice\n0::/user.slice/session-1.scope\n", map[string]string{
+			"name=systemd": "/user.slice",
+			"":             "/user.slice/session-1.scope",
+		}},
+		{"path with colons", "0::/a:b\n", map[string]string{"": "/a:b"}},
+		{"empty", "", map[string]string{}},
+		{"invalid", "not a cgroup\n", map[string]string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if paths := cgroupPaths(test.cgroups); !reflect.DeepEqual(paths, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, paths)
+			}
+		})
+	}
+}
+
+func TestCPUQuota(t *testing.T) {
+	tests := []struct {
+		name     string
+		quota    string
+		period   string
+		expected float64
+	}{
+		{"half", "50000", "100000", 0.5},
+		{"two", "200000", "100000", 2},
+		{"version 2 unlimited", "max", "100000", 0},
+		{"version 1 unlimited", "-1", "100000", 0},
+		{"zero period", "50000", "0", 0},
+		{"missing period", "50000", "", 0},
+		{"invalid", "x", "100000", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if quota := cpuQuota(test.quota, test.period); quota != test.expected {
+				t.Errorf("Expected %f, got %f", test.expected, quota)
+			}
+		})
+	}
+}
+
+func TestParseMemoryStat(t *testing.T) {
+	tests := []struct {
+		name     string
+		stat     string
+		expected map[string]uint64
+	}{
+		{"version 2", memoryStatV2TestFile, map[string]uint64{
+			"anon":       2486272,
+			"file":       1458176,
+			"kernel":     450560,
+			"pgfault":    4189,
+			"pgmajfault": 3,
+		}},
+		{"version 1", memoryStatV1TestFile, map[string]uint64{
+			"cache":                     1458176,
+			"rss":                       2486272,
+			"pgmajfault":                7,
+			"hierarchical_memory_limit": 9223372036854771712,
+			"total_rss":                 2486272,
+		}},
+		{"empty", "", map[string]uint64{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if counters := parseMemoryStat(test.stat); !reflect.DeepEqual(counters, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, counters)
+			}
+		})
+	}
+}
+
+// writeCgroupTestFiles writes files into a fake cgroup file system at root, keyed by their path
+func writeCgroupTestFiles(t *testing.T, root string, files map[string]string) {
+	for name, contents := range files {
+		name = filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("Error creating directory: %s", err.Error())
+		}
+		if err := os.WriteFile(name, []byte(contents), 0644); err != nil {
+			t.Fatalf("Error writing file: %s", err.Error())
+		}
+	}
+}
+
+func TestReadCgroups(t *testing.T) {
+	v2Path := "kubepods.slice/kubepods-burstable.slice/cri-containerd-9b1c.scope/"
+	v2Limits := map[string]string{
+		"memory.max":     "536870912\n",
+		"memory.current": "4194304\n",
+		"memory.stat":    memoryStatV2TestFile,
+		"cpu.max":        "150000 100000\n",
+	}
+	v2Expected := ContainerInfo{
+		CgroupVersion:    2,
+		MemoryLimitBytes: 536870912,
+		MemoryUsageBytes: 4194304,
+		CPUQuota:         1.5,
+		MemoryStat:       parseMemoryStat(memoryStatV2TestFile),
+	}
+	prefixed := func(prefix string, files map[string]string) map[string]string {
+		result := map[string]string{"cgroup.controllers": "cpu memory\n"}
+		for name, contents := range files {
+			result[prefix+name] = contents
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		cgroups  string
+		detected bool
+		expected ContainerInfo
+	}{
+		{
+			name:     "version 2",
+			files:    prefixed(v2Path, v2Limits),
+			cgroups:  cgroupV2TestFile,
+			detected: true,
+			expected: v2Expected,
+		},
+		{
+			name:     "version 2 namespace",
+			files:    prefixed("", v2Limits),
+			cgroups:  "0::/\n",
+			detected: true,
+			expected: v2Expected,
+		},
+		{
+			// The cgroup of the container is mounted at the root, but /proc/self/cgroup has its path on the host
+			name:     "version 2 mounted at the root",
+			files:    prefixed("", v2Limits),
+			cgroups:  cgroupV2TestFile,
+			detected: true,
+			expected: v2Expected,
+		},
+		{
+			// Outside of a container, the files of the root cgroup describe the entire host
+			name:     "version 2 not in a container",
+			files:    prefixed("", v2Limits),
+			cgroups:  "0::/user.slice/session-1.scope\n",
+			expected: ContainerInfo{CgroupVersion: 2},
+		},
+		{
+			name: "version 2 unlimited",
+			files: prefixed(v2Path, map[string]string{
+				"memory.max": "max\n",
+				"cpu.max":    "max 100000\n",
+			}),
+			cgroups:  cgroupV2TestFile,
+			detected: true,
+			expected: ContainerInfo{CgroupVersion: 2},
+		},
+		{
+			name: "version 1",
+			files: map[string]string{
+				"memory/docker/3f2a/memory.limit_in_bytes": "268435456\n",
+				"memory/docker/3f2a/memory.usage_in_bytes": "8388608\n",
+				"memory/docker/3f2a/memory.stat":           memoryStatV1TestFile,
+				"cpu/docker/3f2a/cpu.cfs_quota_us":         "50000\n",
+				"cpu/docker/3f2a/cpu.cfs_period_us":        "100000\n",
+			},
+			cgroups:  cgroupV1TestFile,
+			detected: true,
+			expected: ContainerInfo{
+				CgroupVersion:    1,
+				MemoryLimitBytes: 268435456,
+				MemoryUsageBytes: 8388608,
+				CPUQuota:         0.5,
+				MemoryStat:       parseMemoryStat(memoryStatV1TestFile),
+			},
+		},
+		{
+			name: "version 1 unlimited",
+			files: map[string]string{
+				"memory/docker/3f2a/memory.limit_in_bytes": "9223372036854771712\n",
+				"cpu/docker/3f2a/cpu.cfs_quota_us":         "-1\n",
+				"cpu/docker/3f2a/cpu.cfs_period_us":        "100000\n",
+			},
+			cgroups:  cgroupV1TestFile,
+			detected: true,
+			expected: ContainerInfo{CgroupVersion: 1},
+		},
+		{
+			name: "version 1 mounted at the root",
+			files: map[string]string{
+				"memory/memory.limit_in_bytes": "268435456\n",
+				"cpu/cpu.cfs_quota_us":         "50000\n",
+				"cpu/cpu.cfs_period_us":        "100000\n",
+			},
+			cgroups:  cgroupV1TestFile,
+			detected: true,
+			expected: ContainerInfo{CgroupVersion: 1, MemoryLimitBytes: 268435456, CPUQuota: 0.5},
+		},
+		{
+			name: "version 1 not in a container",
+			files: map[string]string{
+				"memory/memory.stat": memoryStatV1TestFile,
+			},
+			cgroups:  "11:memory:/user.slice\n",
+			expected: ContainerInfo{CgroupVersion: 1},
+		},
+		{
+			name:     "no cgroups",
+			expected: ContainerInfo{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeCgroupTestFiles(t, root, test.files)
+
+			info := ContainerInfo{Detected: test.detected}
+			readCgroups(&info, root, test.cgroups)
+			test.expected.Detected = test.detected
+			if !reflect.DeepEqual(info, test.expected) {
+				t.Errorf("Expected %+v, got %+v", test.expected, info)
+			}
+		})
+	}
+}
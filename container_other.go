@@ -0,0 +1,7 @@
+//go:build !linux
+
+package snapshot
+
+func containerInfo() ContainerInfo {
+	return ContainerInfo{}
+}
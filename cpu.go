@@ -0,0 +1,15 @@
+package snapshot
+
+// CPUInfo describes the CPUs available to the process and how busy the host is
+type CPUInfo struct {
+	// NumCPU is the number of logical CPUs usable by the process
+	NumCPU int `json:"num_cpu"`
+	// GOMAXPROCS is the maximum number of CPUs that can be executing go code simultaneously
+	GOMAXPROCS int `json:"gomaxprocs"`
+	// Load1 is the host's load average over the last minute. Load averages are only populated on Linux.
+	Load1 float64 `json:"load1"`
+	// Load5 is the host's load average over the last 5 minutes
+	Load5 float64 `json:"load5"`
+	// Load15 is the host's load average over the last 15 minutes
+	Load15 float64 `json:"load15"`
+}
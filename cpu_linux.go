@@ -0,0 +1,17 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+)
+
+func loadAverage(info *CPUInfo) error {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Sscanf(string(data), "%f %f %f", &info.Load1, &info.Load5, &info.Load15); err != nil {
+		return err
+	}
+	return nil
+}
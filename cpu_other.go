@@ -0,0 +1,7 @@
+//go:build !linux
+
+package snapshot
+
+func loadAverage(info *CPUInfo) error {
+	return nil
+}
@@ -0,0 +1,145 @@
+package snapshot
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotDiff describes the differences between two snapshots. Numeric fields are the value of the newer snapshot minus
+// the value of the older snapshot.
+type SnapshotDiff struct {
+	// Elapsed is the time between when the two snapshots were collected
+	Elapsed time.Duration
+	// Alloc is the change in bytes of allocated heap objects
+	Alloc int64
+	// TotalAlloc is the number of bytes allocated for heap objects between the snapshots
+	TotalAlloc int64
+	// Sys is the change in total bytes of memory obtained from the OS
+	Sys int64
+	// HeapInuse is the change in bytes in in-use heap spans
+	HeapInuse int64
+	// HeapObjects is the change in the number of allocated heap objects
+	HeapObjects int64
+	// Mallocs is the number of heap objects allocated between the snapshots
+	Mallocs int64
+	// Frees is the number of heap objects freed between the snapshots
+	Frees int64
+	// NumGC is the number of garbage collections between the snapshots
+	NumGC int64
+	// PauseTotal is the total time spent paused for garbage collection between the snapshots
+	PauseTotal time.Duration
+	// NumGoRoutines is the change in the number of goroutines
+	NumGoRoutines int
+	// EnvironChanged is true if the environment variables differ between the snapshots
+	EnvironChanged bool
+	// BuildInfoChanged is true if the snapshots are of different builds
+	BuildInfoChanged bool
+}
+
+// Diff will compare two snapshots and return the differences from old to new.
+func Diff(old, new Snapshot) SnapshotDiff {
+	return SnapshotDiff{
+		Elapsed:          new.Timestamp.Sub(old.Timestamp),
+		Alloc:            int64(new.Memory.Alloc) - int64(old.Memory.Alloc),
+		TotalAlloc:       int64(new.Memory.TotalAlloc) - int64(old.Memory.TotalAlloc),
+		Sys:              int64(new.Memory.Sys) - int64(old.Memory.Sys),
+		HeapInuse:        int64(new.Memory.HeapInuse) - int64(old.Memory.HeapInuse),
+		HeapObjects:      int64(new.Memory.HeapObjects) - int64(old.Memory.HeapObjects),
+		Mallocs:          int64(new.Memory.Mallocs) - int64(old.Memory.Mallocs),
+		Frees:            int64(new.Memory.Frees) - int64(old.Memory.Frees),
+		NumGC:            new.GC.NumGC - old.GC.NumGC,
+		PauseTotal:       new.GC.PauseTotal - old.GC.PauseTotal,
+		NumGoRoutines:    new.NumGoRoutines - old.NumGoRoutines,
+		EnvironChanged:   !sameStrings(old.Environ, new.Environ),
+		BuildInfoChanged: old.BuildInfo.String() != new.BuildInfo.String(),
+	}
+}
+
+// String returns a readable summary of the differences
+func (d SnapshotDiff) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "Elapsed:         %s\n", d.Elapsed)
+	fmt.Fprintf(b, "Alloc:           %s\n", formatBytesDelta(d.Alloc))
+	fmt.Fprintf(b, "TotalAlloc:      %s\n", formatBytesDelta(d.TotalAlloc))
+	fmt.Fprintf(b, "Sys:             %s\n", formatBytesDelta(d.Sys))
+	fmt.Fprintf(b, "HeapInuse:       %s\n", formatBytesDelta(d.HeapInuse))
+	fmt.Fprintf(b, "HeapObjects:     %+d\n", d.HeapObjects)
+	fmt.Fprintf(b, "Mallocs:         %+d\n", d.Mallocs)
+	fmt.Fprintf(b, "Frees:           %+d\n", d.Frees)
+	fmt.Fprintf(b, "NumGC:           %+d\n", d.NumGC)
+	fmt.Fprintf(b, "PauseTotal:      %s\n", d.PauseTotal)
+	fmt.Fprintf(b, "NumGoRoutines:   %+d\n", d.NumGoRoutines)
+	if d.EnvironChanged {
+		b.WriteString("Environment variables changed\n")
+	}
+	if d.BuildInfoChanged {
+		b.WriteString("Build info changed\n")
+	}
+	return b.String()
+}
+
+// ChangedFields will compare the fields of two snapshots that describe the process and the build, and return the names
+// of the fields that differ, such as "Pid" or "Environ". Fields that change every time a snapshot is collected, such as
+// Timestamp, Memory, GC, and Stack, are ignored. This is useful for asserting on snapshots in tests.
+//
+// The fields compared are Pid, PPid, StartTime, Uid, Gid, Username, Hostname, Executable, ExecutableReal, Wd, WdReal,
+// Environ (ignoring order), Extra, BuildInfo, GoVersion, VCSRevision, VCSTime, and VCSModified.
+func (s Snapshot) ChangedFields(other Snapshot) []string {
+	fields := []struct {
+		name string
+		same bool
+	}{
+		{"Pid", s.Pid == other.Pid},
+		{"PPid", s.PPid == other.PPid},
+		{"StartTime", s.StartTime.Equal(other.StartTime)},
+		{"Uid", s.Uid == other.Uid},
+		{"Gid", s.Gid == other.Gid},
+		{"Username", s.Username == other.Username},
+		{"Hostname", s.Hostname == other.Hostname},
+		{"Executable", s.Executable == other.Executable},
+		{"ExecutableReal", s.ExecutableReal == other.ExecutableReal},
+		{"Wd", s.Wd == other.Wd},
+		{"WdReal", s.WdReal == other.WdReal},
+		{"Environ", sameStrings(s.Environ, other.Environ)},
+		{"Extra", reflect.DeepEqual(s.Extra, other.Extra)},
+		{"BuildInfo", s.BuildInfo.String() == other.BuildInfo.String()},
+		{"GoVersion", s.GoVersion == other.GoVersion},
+		{"VCSRevision", s.VCSRevision == other.VCSRevision},
+		{"VCSTime", s.VCSTime == other.VCSTime},
+		{"VCSModified", s.VCSModified == other.VCSModified},
+	}
+
+	changed := []string{}
+	for _, field := range fields {
+		if !field.same {
+			changed = append(changed, field.name)
+		}
+	}
+	return changed
+}
+
+// Equal returns true if none of the fields compared by ChangedFields differ between the snapshots
+func (s Snapshot) Equal(other Snapshot) bool {
+	return len(s.ChangedFields(other)) == 0
+}
+
+// sameStrings returns true if a and b contain the same strings, ignoring order
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
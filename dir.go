@@ -0,0 +1,78 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FullDir will take a full detailed snapshot of your go application, like Full, and write each file of the snapshot
+// into dir instead of a ZIP file, so that they can be opened right away, such as with `go tool pprof dir/heap.pprof`.
+// dir and any of its parents are created if they don't exist. Existing files in dir with the same names are replaced.
+//
+// Warning: this will temporarily suspend all execution of your application while the heap dump is written.
+func FullDir(dir string) error {
+	return FullDirWithOptions(dir, DefaultOptions())
+}
+
+// FullDirWithOptions will take a snapshot of your go application containing only the artifacts selected by opts, like
+// FullWithOptions, and write each file into dir. opts.CompressionLevel is not used. If an error is returned, the files
+// that were already written are left in dir.
+func FullDirWithOptions(dir string, opts Options) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return stageErr(ErrOpen, err)
+	}
+
+	opts = opts.withTempDir(dir)
+	d := &dirArchive{dir: dir}
+	if err := writeArchive(context.Background(), d, opts); err != nil {
+		d.close()
+		return err
+	}
+	if err := d.close(); err != nil {
+		return stageErr(ErrClose, err)
+	}
+	return nil
+}
+
+// dirArchive is an archive that writes each file into a directory
+type dirArchive struct {
+	dir     string
+	current *os.File
+}
+
+func (d *dirArchive) Create(name string) (io.Writer, error) {
+	if err := d.close(); err != nil {
+		return nil, err
+	}
+
+	fileName := filepath.Join(d.dir, filepath.FromSlash(name))
+	if rel, err := filepath.Rel(d.dir, fileName); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%q is outside of %s", name, d.dir)
+	}
+	if err := os.MkdirAll(filepath.Dir(fileName), os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	d.current = f
+	return f, nil
+}
+
+// close closes the file currently being written, if any
+func (d *dirArchive) close() error {
+	if d.current == nil {
+		return nil
+	}
+	err := d.current.Close()
+	d.current = nil
+	return err
+}
@@ -0,0 +1,19 @@
+package snapshot
+
+// DiskStats describes the usage of a filesystem
+type DiskStats struct {
+	// Path is the path that the statistics were collected for
+	Path string `json:"path"`
+	// Total is the size of the filesystem in bytes
+	Total uint64 `json:"total"`
+	// Free is the number of free bytes on the filesystem
+	Free uint64 `json:"free"`
+	// Available is the number of free bytes available to the process, which may be less than Free
+	Available uint64 `json:"available"`
+}
+
+// DiskUsage returns the usage of the filesystem containing path. This is supported on Linux, macOS, FreeBSD, DragonFly
+// BSD, and Windows. On other platforms only the path is populated.
+func DiskUsage(path string) (DiskStats, error) {
+	return diskUsage(path)
+}
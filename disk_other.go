@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !windows
+
+package snapshot
+
+func diskUsage(path string) (DiskStats, error) {
+	return DiskStats{Path: path}, nil
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package snapshot
+
+import "syscall"
+
+func diskUsage(path string) (DiskStats, error) {
+	stats := DiskStats{Path: path}
+
+	st := syscall.Statfs_t{}
+	if err := syscall.Statfs(path, &st); err != nil {
+		return stats, err
+	}
+	stats.Total = uint64(st.Blocks) * uint64(st.Bsize)
+	stats.Free = uint64(st.Bfree) * uint64(st.Bsize)
+	stats.Available = uint64(st.Bavail) * uint64(st.Bsize)
+	return stats, nil
+}
@@ -0,0 +1,30 @@
+package snapshot
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceExW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+func diskUsage(path string) (DiskStats, error) {
+	stats := DiskStats{Path: path}
+
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return stats, err
+	}
+	var available, total, free uint64
+	ret, _, err := procGetDiskFreeSpaceExW.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&available)),
+		uintptr(unsafe.Pointer(&total)),
+		uintptr(unsafe.Pointer(&free)))
+	if ret == 0 {
+		return stats, err
+	}
+	stats.Total = total
+	stats.Free = free
+	stats.Available = available
+	return stats, nil
+}
@@ -0,0 +1,271 @@
+package snapshot
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// An encrypted snapshot starts with encryptedMagic, followed by the length of the wrapped key as a big endian uint16
+// and the AES-256 key wrapped with RSA-OAEP using SHA-256. The rest of the file is a sequence of chunks of at most
+// encryptedChunkSize bytes of plaintext, each sealed with AES-GCM and prefixed with its sealed length as a big endian
+// uint32. The nonce of each chunk is its sequence number, and the last chunk is marked in its additional data so that a
+// truncated file is detected.
+const (
+	encryptedMagic     = "SNAPENC1"
+	encryptedChunkSize = 64 * 1024
+	encryptedKeySize   = 32
+)
+
+var (
+	encryptedLabel     = []byte("snapshot")
+	encryptedLastChunk = []byte{1}
+	encryptedChunk     = []byte{0}
+)
+
+// ErrDecrypt is returned by Decrypt, or when reading from the reader it returns, if an encrypted snapshot can't be
+// decrypted because it was encrypted for a different key, or it was modified or truncated.
+var ErrDecrypt = errors.New("snapshot: unable to decrypt")
+
+// FullEncrypted will take a full detailed snapshot of your go application, like Full, and save it as a ZIP file that is
+// encrypted for key at the given path. fileName must end with ".zip.enc". Only the holder of the private key can read
+// the snapshot, use Decrypt to get the ZIP file back.
+//
+// Snapshots contain the environment and the entire heap of your application, which may include secrets or personal
+// information, encrypting them makes it safe to keep them in shared storage. The archive is encrypted with AES-256-GCM
+// using a random key, which is itself encrypted with key using RSA-OAEP.
+//
+// Warning: this will temporarily suspend all execution of your application while the heap dump is written.
+func FullEncrypted(fileName string, key *rsa.PublicKey) error {
+	return FullEncryptedWithOptions(fileName, key, DefaultOptions())
+}
+
+// FullEncryptedWithOptions will take a snapshot of your go application containing only the artifacts selected by opts,
+// like FullWithOptions, and save it as a ZIP file that is encrypted for key at the given path, like FullEncrypted.
+func FullEncryptedWithOptions(fileName string, key *rsa.PublicKey, opts Options) error {
+	if err := checkFileName(fileName, ".zip.enc"); err != nil {
+		return err
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	opts = opts.withTempDir(filepath.Dir(fileName))
+	return writeFileAtomic(fileName, func(w io.Writer) error {
+		ew, err := Encrypt(w, key)
+		if err != nil {
+			return stageErr(ErrEncrypt, err)
+		}
+		if err := FullToContextWithOptions(context.Background(), ew, opts); err != nil {
+			return err
+		}
+		if err := ew.Close(); err != nil {
+			return stageErr(ErrEncrypt, err)
+		}
+		return nil
+	})
+}
+
+// Encrypt returns a writer that encrypts everything written to it for key, in the same format as FullEncrypted, and
+// writes it to w. Close must be called once everything has been written to finish the encrypted file, it does not
+// close w. Use this to encrypt the output of FullTo:
+//
+//	ew, err := snapshot.Encrypt(w, key)
+//	err = snapshot.FullTo(ew)
+//	err = ew.Close()
+func Encrypt(w io.Writer, key *rsa.PublicKey) (io.WriteCloser, error) {
+	aesKey := make([]byte, encryptedKeySize)
+	if _, err := rand.Read(aesKey); err != nil {
+		return nil, err
+	}
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, key, aesKey, encryptedLabel)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newEncryptedAEAD(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, len(encryptedMagic)+2+len(wrappedKey))
+	header = append(header, encryptedMagic...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrappedKey)))
+	header = append(header, wrappedKey...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{w: w, aead: aead, buf: make([]byte, 0, encryptedChunkSize)}, nil
+}
+
+// Decrypt returns a reader of the ZIP file in an encrypted snapshot written by FullEncrypted or Encrypt. The header is
+// read and the key is decrypted before Decrypt returns, the rest of r is decrypted as it is read. An error wrapping
+// ErrDecrypt is returned, either by Decrypt or when reading, if r was not encrypted for key or has been modified.
+//
+//	f, err := os.Open("debug.zip.enc")
+//	r, err := snapshot.Decrypt(f, key)
+//	out, err := os.Create("debug.zip")
+//	_, err = io.Copy(out, r)
+func Decrypt(r io.Reader, key *rsa.PrivateKey) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(encryptedMagic)+2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("%w: reading header: %s", ErrDecrypt, err.Error())
+	}
+	if string(header[:len(encryptedMagic)]) != encryptedMagic {
+		return nil, fmt.Errorf("%w: not an encrypted snapshot", ErrDecrypt)
+	}
+	wrappedKey := make([]byte, binary.BigEndian.Uint16(header[len(encryptedMagic):]))
+	if _, err := io.ReadFull(br, wrappedKey); err != nil {
+		return nil, fmt.Errorf("%w: reading key: %s", ErrDecrypt, err.Error())
+	}
+	aesKey, err := rsa.DecryptOAEP(sha256.New(), nil, key, wrappedKey, encryptedLabel)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDecrypt, err.Error())
+	}
+	aead, err := newEncryptedAEAD(aesKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDecrypt, err.Error())
+	}
+	return &decryptReader{r: br, aead: aead}, nil
+}
+
+func newEncryptedAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of the chunk with the given sequence number. Every file has its own random key, so the
+// nonce only needs to be unique within a file.
+func chunkNonce(aead cipher.AEAD, sequence uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], sequence)
+	return nonce
+}
+
+type encryptWriter struct {
+	w        io.Writer
+	aead     cipher.AEAD
+	buf      []byte
+	sequence uint64
+	closed   bool
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed encrypted writer")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		n := copy(e.buf[len(e.buf):cap(e.buf)], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+		// The last chunk is only written by Close, so a full buffer is held back until there is more to write
+		if len(e.buf) == cap(e.buf) && len(p) > 0 {
+			if err := e.seal(encryptedChunk); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close writes the last chunk, without closing the underlying writer
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(encryptedLastChunk)
+}
+
+// seal encrypts and writes the buffered chunk
+func (e *encryptWriter) seal(additionalData []byte) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.aead, e.sequence), e.buf, additionalData)
+	e.sequence++
+	e.buf = e.buf[:0]
+
+	length := binary.BigEndian.AppendUint32(nil, uint32(len(sealed)))
+	if _, err := e.w.Write(length); err != nil {
+		return err
+	}
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+type decryptReader struct {
+	r        *bufio.Reader
+	aead     cipher.AEAD
+	chunk    []byte
+	sequence uint64
+	last     bool
+	err      error
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.chunk) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.last {
+			d.err = d.checkEnd()
+			continue
+		}
+		d.err = d.open()
+	}
+
+	n := copy(p, d.chunk)
+	d.chunk = d.chunk[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next chunk
+func (d *decryptReader) open() error {
+	length := make([]byte, 4)
+	if _, err := io.ReadFull(d.r, length); err != nil {
+		return fmt.Errorf("%w: truncated", ErrDecrypt)
+	}
+	size := binary.BigEndian.Uint32(length)
+	if size > encryptedChunkSize+uint32(d.aead.Overhead()) {
+		return fmt.Errorf("%w: invalid chunk length %d", ErrDecrypt, size)
+	}
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return fmt.Errorf("%w: truncated", ErrDecrypt)
+	}
+
+	nonce := chunkNonce(d.aead, d.sequence)
+	d.sequence++
+	chunk, err := d.aead.Open(nil, nonce, sealed, encryptedChunk)
+	if err != nil {
+		chunk, err = d.aead.Open(nil, nonce, sealed, encryptedLastChunk)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrDecrypt, err.Error())
+		}
+		d.last = true
+	}
+	d.chunk = chunk
+	return nil
+}
+
+// checkEnd returns io.EOF if there is nothing after the last chunk
+func (d *decryptReader) checkEnd() error {
+	if _, err := d.r.ReadByte(); err != io.EOF {
+		return fmt.Errorf("%w: unexpected data after the end", ErrDecrypt)
+	}
+	return io.EOF
+}
@@ -0,0 +1,230 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func generateTestKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating key: %s", err.Error())
+	}
+	return key
+}
+
+func encryptTestData(t *testing.T, key *rsa.PrivateKey, plaintext []byte) []byte {
+	buf := &bytes.Buffer{}
+	ew, err := Encrypt(buf, &key.PublicKey)
+	if err != nil {
+		t.Fatalf("Error encrypting: %s", err.Error())
+	}
+	if _, err := ew.Write(plaintext); err != nil {
+		t.Fatalf("Error encrypting: %s", err.Error())
+	}
+	if err := ew.Close(); err != nil {
+		t.Fatalf("Error encrypting: %s", err.Error())
+	}
+	return buf.Bytes()
+}
+
+// splitEncrypted splits an encrypted file into its header and its chunks, each including its length
+func splitEncrypted(t *testing.T, data []byte) ([]byte, [][]byte) {
+	keyLength := int(binary.BigEndian.Uint16(data[len(encryptedMagic):]))
+	headerLength := len(encryptedMagic) + 2 + keyLength
+	header, rest := data[:headerLength], data[headerLength:]
+
+	chunks := [][]byte{}
+	for len(rest) > 0 {
+		if len(rest) < 4 {
+			t.Fatalf("Invalid chunk length")
+		}
+		chunkLength := 4 + int(binary.BigEndian.Uint32(rest))
+		chunks = append(chunks, rest[:chunkLength])
+		rest = rest[chunkLength:]
+	}
+	return header, chunks
+}
+
+func joinEncrypted(header []byte, chunks ...[]byte) []byte {
+	data := append([]byte{}, header...)
+	for _, chunk := range chunks {
+		data = append(data, chunk...)
+	}
+	return data
+}
+
+func randomTestData(t *testing.T, size int) []byte {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("Error generating data: %s", err.Error())
+	}
+	return data
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	key := generateTestKey(t)
+
+	tests := []struct {
+		name   string
+		size   int
+		chunks int
+	}{
+		{"empty", 0, 1},
+		{"small", 100, 1},
+		{"one chunk", encryptedChunkSize, 1},
+		{"one chunk and a byte", encryptedChunkSize + 1, 2},
+		{"several chunks", 3*encryptedChunkSize + 123, 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plaintext := randomTestData(t, test.size)
+			data := encryptTestData(t, key, plaintext)
+			if _, chunks := splitEncrypted(t, data); len(chunks) != test.chunks {
+				t.Errorf("Expected %d chunks, got %d", test.chunks, len(chunks))
+			}
+
+			r, err := Decrypt(bytes.NewReader(data), key)
+			if err != nil {
+				t.Fatalf("Error decrypting: %s", err.Error())
+			}
+			decrypted, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatalf("Error decrypting: %s", err.Error())
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Decrypted data does not match, got %d bytes, expected %d", len(decrypted), len(plaintext))
+			}
+		})
+	}
+}
+
+func TestEncryptInvalid(t *testing.T) {
+	key := generateTestKey(t)
+	plaintext := randomTestData(t, 3*encryptedChunkSize+123)
+	data := encryptTestData(t, key, plaintext)
+	header, chunks := splitEncrypted(t, data)
+	if len(chunks) != 4 {
+		t.Fatalf("Expected 4 chunks, got %d", len(chunks))
+	}
+
+	tampered := append([]byte{}, chunks[1]...)
+	tampered[len(tampered)/2] ^= 0xff
+	tamperedLength := append([]byte{}, chunks[1]...)
+	binary.BigEndian.PutUint32(tamperedLength, uint32(len(chunks[1])-5))
+
+	tests := []struct {
+		name string
+		data []byte
+		// chunks is the number of chunks that are authentic and may be read before the error
+		chunks int
+	}{
+		{"tampered chunk", joinEncrypted(header, chunks[0], tampered, chunks[2], chunks[3]), 1},
+		{"tampered length", joinEncrypted(header, chunks[0], tamperedLength, chunks[2], chunks[3]), 1},
+		{"missing last chunk", joinEncrypted(header, chunks[0], chunks[1], chunks[2]), 3},
+		{"truncated last chunk", data[:len(data)-1], 3},
+		{"truncated length", joinEncrypted(header, chunks[0], chunks[1][:2]), 1},
+		{"reordered chunks", joinEncrypted(header, chunks[1], chunks[0], chunks[2], chunks[3]), 0},
+		{"dropped chunk", joinEncrypted(header, chunks[0], chunks[2], chunks[3]), 1},
+		{"repeated last chunk", joinEncrypted(header, chunks[0], chunks[1], chunks[2], chunks[3], chunks[3]), 4},
+		{"data after the end", append(append([]byte{}, data...), 0), 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := Decrypt(bytes.NewReader(test.data), key)
+			if err != nil {
+				t.Fatalf("Error decrypting: %s", err.Error())
+			}
+			decrypted, err := io.ReadAll(r)
+			if !errors.Is(err, ErrDecrypt) {
+				t.Fatalf("Expected ErrDecrypt, got %v", err)
+			}
+			if len(decrypted) > test.chunks*encryptedChunkSize {
+				t.Errorf("Expected at most %d authentic chunks before the error, got %d bytes", test.chunks, len(decrypted))
+			}
+			if !bytes.Equal(decrypted, plaintext[:len(decrypted)]) {
+				t.Errorf("Decrypted data before the error is not the plaintext")
+			}
+		})
+	}
+}
+
+func TestDecryptWrongKey(t *testing.T) {
+	key := generateTestKey(t)
+	data := encryptTestData(t, key, randomTestData(t, 100))
+
+	r, err := Decrypt(bytes.NewReader(data), generateTestKey(t))
+	if !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("Expected ErrDecrypt, got %v", err)
+	}
+	if r != nil {
+		t.Errorf("Expected no reader for the wrong key")
+	}
+}
+
+func TestDecryptInvalidHeader(t *testing.T) {
+	key := generateTestKey(t)
+	data := encryptTestData(t, key, randomTestData(t, 100))
+	header, _ := splitEncrypted(t, data)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not encrypted", []byte("PK\x03\x04 not an encrypted snapshot")},
+		{"truncated key", header[:len(header)-1]},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Decrypt(bytes.NewReader(test.data), key); !errors.Is(err, ErrDecrypt) {
+				t.Errorf("Expected ErrDecrypt, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFullEncrypted(t *testing.T) {
+	key := generateTestKey(t)
+	fileName := filepath.Join(t.TempDir(), "debug.zip.enc")
+	opts := DefaultOptions()
+	opts.IncludeHeapDump = false
+	if err := FullEncryptedWithOptions(fileName, &key.PublicKey, opts); err != nil {
+		t.Fatalf("Error taking snapshot: %s", err.Error())
+	}
+
+	f, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("Error opening snapshot: %s", err.Error())
+	}
+	defer f.Close()
+	r, err := Decrypt(f, key)
+	if err != nil {
+		t.Fatalf("Error decrypting: %s", err.Error())
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Error decrypting: %s", err.Error())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Error reading decrypted snapshot: %s", err.Error())
+	}
+	found := false
+	for _, file := range zr.File {
+		if file.Name == "snapshot.json" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("snapshot.json not found in decrypted snapshot")
+	}
+}
@@ -0,0 +1,36 @@
+package snapshot
+
+import "strings"
+
+// EnvMap returns the environment variables in Environ keyed by name, which makes it easy to look up a single variable.
+// If a variable is set more than once, the first value is used, which is the value os.Getenv returns. Values may
+// contain "=", only the first "=" separates the name from the value. Entries without one are left out. Variables on
+// Windows whose name starts with "=", such as "=C:", are kept. Encoded as JSON, the variables are sorted by name.
+func (s Snapshot) EnvMap() map[string]string {
+	env := make(map[string]string, len(s.Environ))
+	for _, entry := range s.Environ {
+		name, value, ok := splitEnv(entry)
+		if !ok {
+			continue
+		}
+		if _, seen := env[name]; !seen {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// splitEnv splits an entry of the environment into its name and value. The name may start with "=", as it does for the
+// hidden variables that hold the working directory of each drive on Windows.
+func splitEnv(entry string) (string, string, bool) {
+	start := 0
+	if strings.HasPrefix(entry, "=") {
+		start = 1
+	}
+	i := strings.Index(entry[start:], "=")
+	if i < 0 {
+		return "", "", false
+	}
+	i += start
+	return entry[:i], entry[i+1:], true
+}
@@ -0,0 +1,140 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors returned by Full and its variants, and by Recorder, wrap one of the following to identify the stage that
+// failed, along with the underlying error. Use errors.Is to check for them, for example to tell a heap dump that failed
+// because the disk is full from a permission error opening the output file:
+//
+//	if errors.Is(err, snapshot.ErrOpen) && errors.Is(err, fs.ErrPermission) {
+//		...
+//	}
+//
+// If the context given to FullContext is cancelled, the context's error is returned as-is instead.
+var (
+	// ErrOpen is returned when the output file could not be created
+	ErrOpen = errors.New("open")
+	// ErrClose is returned when the output file could not be closed, or renamed over the existing file
+	ErrClose = errors.New("close")
+	// ErrZip is returned when the archive could not be finished, or the compression level is invalid
+	ErrZip = errors.New("zip")
+	// ErrTar is returned when a tar.gz archive, or a file within it, could not be written
+	ErrTar = errors.New("tar")
+	// ErrCPUProfile is returned when cpu.pprof could not be written
+	ErrCPUProfile = errors.New("cpu profile")
+	// ErrTrace is returned when trace.out could not be written
+	ErrTrace = errors.New("execution trace")
+	// ErrSnapshotJSON is returned when snapshot.json could not be written
+	ErrSnapshotJSON = errors.New("snapshot")
+	// ErrSummary is returned when summary.json could not be written
+	ErrSummary = errors.New("summary")
+	// ErrStack is returned when stack.txt could not be written
+	ErrStack = errors.New("goroutine stacks")
+	// ErrCallerStack is returned when caller-stack.txt could not be written
+	ErrCallerStack = errors.New("caller stack")
+	// ErrSched is returned when sched.txt could not be written
+	ErrSched = errors.New("scheduler")
+	// ErrBlockProfile is returned when block.pprof could not be written
+	ErrBlockProfile = errors.New("block profile")
+	// ErrMutexProfile is returned when mutex.pprof could not be written
+	ErrMutexProfile = errors.New("mutex profile")
+	// ErrHeapProfile is returned when heap.pprof could not be written
+	ErrHeapProfile = errors.New("heap profile")
+	// ErrProfile is returned when one of Options.IncludeProfiles could not be written, or does not exist
+	ErrProfile = errors.New("profile")
+	// ErrHeapDump is returned when the heap dump could not be taken or heap.bin could not be written
+	ErrHeapDump = errors.New("dump")
+	// ErrExtraFiles is returned when one of Options.ExtraFiles could not be copied into the archive
+	ErrExtraFiles = errors.New("extra files")
+	// ErrManifest is returned when manifest.json could not be written
+	ErrManifest = errors.New("manifest")
+	// ErrEncrypt is returned when an encrypted snapshot could not be written
+	ErrEncrypt = errors.New("encrypt")
+	// ErrRecorderClosed is returned by Recorder.Capture after the recorder was closed
+	ErrRecorderClosed = errors.New("recorder: closed")
+)
+
+// ErrInvalidExtension is returned, wrapped with ErrOpen, when the file name given for a snapshot archive does not end
+// with the extension of the archive format, such as ".zip" or ".tar.gz". No file is created or truncated when this is
+// returned.
+var ErrInvalidExtension = errors.New("invalid file name extension")
+
+// ErrInvalidPath is returned, wrapped with ErrExtraFiles or ErrOpen, when a key of Options.ExtraFiles or
+// Options.Prefix is not a clean relative path within the archive, such as one containing "..", or a key of
+// Options.ExtraFiles has the name of an artifact of the snapshot. This is checked for every archive format before
+// anything is written.
+var ErrInvalidPath = errors.New("invalid path in archive")
+
+// PartialError is returned when a snapshot was only partly taken, along with whatever was collected before the error.
+// It wraps the underlying error, so errors.Is still matches the stage that failed. Use errors.As to get the partial
+// results:
+//
+//	var partial *snapshot.PartialError
+//	if errors.As(err, &partial) && partial.Snapshot != nil {
+//		...
+//	}
+type PartialError struct {
+	// Snapshot is the information that was collected. CollectE always sets it, the Full variants only set it if it was
+	// collected for snapshot.json or summary.json before the error.
+	Snapshot *Snapshot
+	// Artifacts are the names of the files in the archive that were completely written before the stage that failed,
+	// in the order they were written. Only set by the Full variants. Full and the other variants that write to a file
+	// remove the incomplete archive, but FullTo and WriteTo leave these files in the archive.
+	Artifacts []string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *PartialError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// ErrInvalidInterval is returned by Monitor.Start, StreamJSON and WatchGoroutines when the interval is not positive
+var ErrInvalidInterval = errors.New("snapshot: interval must be positive")
+
+// ErrTooSoon is returned by Full and its variants, without taking a snapshot, when they are called again before the
+// minimum interval set by SetMinInterval has passed.
+var ErrTooSoon = errors.New("snapshot: too soon after the previous snapshot")
+
+// stageError is an error that occurred during one stage of writing a snapshot. It matches both the sentinel error of
+// the stage and the underlying error with errors.Is.
+type stageError struct {
+	stage error
+	err   error
+}
+
+func (e *stageError) Error() string {
+	return e.stage.Error() + ": " + e.err.Error()
+}
+
+func (e *stageError) Unwrap() error {
+	return e.err
+}
+
+func (e *stageError) Is(target error) bool {
+	return target == e.stage
+}
+
+// stageErr wraps err with the sentinel error of stage
+func stageErr(stage error, err error) error {
+	return &stageError{stage: stage, err: err}
+}
+
+// checkFileName returns an error wrapping ErrInvalidExtension if fileName does not end with any of extensions, ignoring
+// case
+func checkFileName(fileName string, extensions ...string) error {
+	for _, extension := range extensions {
+		if len(fileName) > len(extension) && strings.EqualFold(fileName[len(fileName)-len(extension):], extension) {
+			return nil
+		}
+	}
+	return stageErr(ErrOpen, fmt.Errorf("%w: %q must end with %s", ErrInvalidExtension, fileName, strings.Join(extensions, " or ")))
+}
@@ -0,0 +1,11 @@
+package snapshot
+
+// EstimateFullSize returns a rough upper bound of the size in bytes of the archive written by Full, before
+// compression. It is dominated by the heap dump, which is at most the amount of memory obtained from the OS by the go
+// runtime. Use it to check that there is enough free disk space before taking a snapshot, and leave out the heap dump
+// with FullWithOptions if there isn't. See DiskUsage, and FullPlan for the size of each file.
+//
+// Unlike Collect, this does not pause execution.
+func EstimateFullSize() int64 {
+	return FullPlan(DefaultOptions()).EstimatedBytes
+}
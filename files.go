@@ -0,0 +1,18 @@
+package snapshot
+
+// OpenFile describes a file descriptor open in the process
+type OpenFile struct {
+	// FD is the file descriptor number
+	FD int `json:"fd"`
+	// Target is what the file descriptor refers to. For files this is the path of the file, for sockets and pipes this
+	// is a description such as "socket:[12345]" or "pipe:[12345]".
+	Target string `json:"target"`
+}
+
+// FDLimit is the limit on the number of file descriptors the process can have open, RLIMIT_NOFILE. Opening a file or
+// socket fails with "too many open files" once Soft is reached. The process can raise Soft up to Hard. Limits are
+// math.MaxUint64 if they are unlimited.
+type FDLimit struct {
+	Soft uint64 `json:"soft"`
+	Hard uint64 `json:"hard"`
+}
@@ -0,0 +1,52 @@
+package snapshot
+
+import (
+	"os"
+	"sort"
+	"strconv"
+)
+
+func openFiles() ([]OpenFile, error) {
+	return readOpenFiles("/proc/self")
+}
+
+// readOpenFiles returns the file descriptors of the process whose /proc directory is procDir
+func readOpenFiles(procDir string) ([]OpenFile, error) {
+	entries, err := os.ReadDir(procDir + "/fd")
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]OpenFile, 0, len(entries))
+	for _, entry := range entries {
+		fd, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// The descriptor used to read the directory will have been closed by now, readlink fails for it
+		target, err := os.Readlink(procDir + "/fd/" + entry.Name())
+		if err != nil {
+			continue
+		}
+		files = append(files, OpenFile{FD: fd, Target: target})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].FD < files[j].FD
+	})
+	return files, nil
+}
+
+// numFDs returns the number of file descriptors open in the process
+func numFDs() (int, error) {
+	f, err := os.Open("/proc/self/fd")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return 0, err
+	}
+	// Leave out the descriptor used to read the directory
+	return len(names) - 1, nil
+}
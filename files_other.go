@@ -0,0 +1,11 @@
+//go:build !linux
+
+package snapshot
+
+func openFiles() ([]OpenFile, error) {
+	return nil, nil
+}
+
+func numFDs() (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,48 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+)
+
+// Fingerprint returns a short hex digest of the parts of the snapshot that stay the same between processes running
+// the same build in the same kind of environment: the build information, the names (but not the values) of the
+// environment variables, and the set of functions that goroutines are in. Snapshots from the same deployment that are
+// doing the same work have the same fingerprint even though their memory statistics, timestamps, and goroutine counts
+// differ, so it can be used to group snapshots collected from many processes.
+func (s Snapshot) Fingerprint() string {
+	envNames := make([]string, 0, len(s.Environ))
+	for _, env := range s.Environ {
+		if name, _, ok := splitEnv(env); ok {
+			envNames = append(envNames, name)
+		}
+	}
+	sort.Strings(envNames)
+
+	functions := map[string]bool{}
+	for _, g := range s.Goroutines {
+		functions[g.TopFunction] = true
+	}
+	topFunctions := make([]string, 0, len(functions))
+	for function := range functions {
+		topFunctions = append(topFunctions, function)
+	}
+	sort.Strings(topFunctions)
+
+	hash := sha256.New()
+	// Each part is terminated with a NUL and each list is prefixed with its length, so that parts can't run together
+	write := func(parts ...string) {
+		for _, part := range parts {
+			hash.Write([]byte(part))
+			hash.Write([]byte{0})
+		}
+	}
+	write(s.GoVersion, s.BuildInfo.String())
+	write(strconv.Itoa(len(envNames)))
+	write(envNames...)
+	write(strconv.Itoa(len(topFunctions)))
+	write(topFunctions...)
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
@@ -0,0 +1,27 @@
+package snapshot
+
+import "fmt"
+
+// formatBytes returns a human readable representation of n bytes using binary units, such as "128.4 MiB"
+func formatBytes(n uint64) string {
+	units := []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	value := float64(n) / 1024
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// formatBytesDelta returns a human readable representation of a signed change of n bytes, such as "+128.4 MiB"
+func formatBytesDelta(n int64) string {
+	if n < 0 {
+		return "-" + formatBytes(uint64(-n))
+	}
+	return "+" + formatBytes(uint64(n))
+}
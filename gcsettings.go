@@ -0,0 +1,57 @@
+package snapshot
+
+import (
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+)
+
+// readGCSettings sets the soft memory limit and GOGC value the garbage collector is currently using, without changing
+// either of them
+func readGCSettings(s *Snapshot) {
+	// A negative limit only returns the current limit
+	s.MemoryLimit = debug.SetMemoryLimit(-1)
+	s.GCPercent = gcPercent()
+}
+
+// gcPercent returns the current GOGC value, or -1 if the garbage collector is disabled
+func gcPercent() int {
+	sample := []metrics.Sample{{Name: "/gc/gogc:percent"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() == metrics.KindUint64 {
+		return int(int64(sample[0].Value.Uint64()))
+	}
+
+	// Versions of go before 1.21 don't report the metric, and the only way to read the value is to change it. Restore
+	// it immediately, the garbage collector is only disabled for an instant.
+	percent := debug.SetGCPercent(-1)
+	debug.SetGCPercent(percent)
+	return percent
+}
+
+// memoryPressureThreshold is the MemoryPressure at which IsUnderMemoryPressure returns true
+const memoryPressureThreshold = 0.9
+
+// memoryPressure returns the memory used by the go runtime as a fraction of the lowest of the soft memory limit and the
+// memory limit of the container, or 0 if there is no limit
+func memoryPressure(s Snapshot) float64 {
+	limit := int64(0)
+	if s.MemoryLimit > 0 && s.MemoryLimit < math.MaxInt64 {
+		limit = s.MemoryLimit
+	}
+	if containerLimit := s.Container.MemoryLimitBytes; containerLimit > 0 && (limit == 0 || containerLimit < limit) {
+		limit = containerLimit
+	}
+	if limit == 0 {
+		return 0
+	}
+	// This is the same measure of memory use that the runtime compares with the soft memory limit
+	return float64(s.Memory.Sys-s.Memory.HeapReleased) / float64(limit)
+}
+
+// IsUnderMemoryPressure returns true if the process is using at least 90% of its memory limit, see MemoryPressure. A
+// process under memory pressure spends more time collecting garbage, and is close to being killed for running out of
+// memory if the limit is the limit of its container.
+func (s Snapshot) IsUnderMemoryPressure() bool {
+	return s.MemoryPressure >= memoryPressureThreshold
+}
@@ -0,0 +1,355 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GitInfo describes the git repository containing the working directory of the process, as it was when the snapshot
+// was collected. Unlike VCSRevision and VCSModified, which describe the source the application was built from, this
+// describes the repository on disk, which may have changed since the build.
+type GitInfo struct {
+	// Root is the top level directory of the working tree
+	Root string `json:"root"`
+	// Head is the SHA of the commit checked out, or empty if nothing has been committed on Branch yet
+	Head string `json:"head,omitempty"`
+	// Branch is the name of the branch checked out, or empty if HEAD is detached
+	Branch string `json:"branch,omitempty"`
+	// Dirty is true if a tracked file in the working tree differs from the index, or has a merge conflict, like
+	// `git diff --quiet` would report. Untracked files and changes staged in the index are not considered.
+	Dirty bool `json:"dirty"`
+}
+
+// gitIndexEntry is the part of an entry of the git index that is needed to check if a file has changed
+type gitIndexEntry struct {
+	name     string
+	mtime    uint32
+	mtimeNs  uint32
+	mode     uint32
+	size     uint32
+	hash     []byte
+	stage    uint16
+	unstaged bool
+	skip     bool
+}
+
+const (
+	gitModeTypeMask = 0170000
+	gitModeSymlink  = 0120000
+	gitModeGitlink  = 0160000
+)
+
+// readGit reads the git repository containing dir by reading its files directly, without running git. nil is
+// returned if dir is not within a git repository or it could not be read. If the working tree could not be compared
+// with the index, Dirty is false.
+func readGit(dir string) *GitInfo {
+	root, gitDir, err := findGitDir(dir)
+	if err != nil {
+		return nil
+	}
+	commonDir := gitDir
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		commonDir = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+	}
+
+	headData, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return nil
+	}
+	info := &GitInfo{Root: root}
+	head := strings.TrimSpace(string(headData))
+	if strings.HasPrefix(head, "ref: ") {
+		ref := strings.TrimPrefix(head, "ref: ")
+		info.Branch = strings.TrimPrefix(ref, "refs/heads/")
+		info.Head = resolveGitRef(gitDir, commonDir, ref)
+	} else {
+		info.Head = head
+	}
+
+	if dirty, err := gitDirty(root, gitDir, commonDir); err == nil {
+		info.Dirty = dirty
+	}
+	return info
+}
+
+// findGitDir returns the top level directory of the working tree containing dir, and its git directory. In a linked
+// worktree or a submodule, .git is a file containing the path of the git directory.
+func findGitDir(dir string) (string, string, error) {
+	for {
+		gitPath := filepath.Join(dir, ".git")
+		if stat, err := os.Stat(gitPath); err == nil {
+			if stat.IsDir() {
+				return dir, gitPath, nil
+			}
+			data, err := os.ReadFile(gitPath)
+			if err != nil {
+				return "", "", err
+			}
+			gitDir := strings.TrimSpace(string(data))
+			if !strings.HasPrefix(gitDir, "gitdir: ") {
+				return "", "", fmt.Errorf("invalid .git file")
+			}
+			gitDir = strings.TrimPrefix(gitDir, "gitdir: ")
+			if !filepath.IsAbs(gitDir) {
+				gitDir = filepath.Join(dir, gitDir)
+			}
+			return dir, gitDir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", os.ErrNotExist
+		}
+		dir = parent
+	}
+}
+
+// resolveGitRef returns the SHA of the commit that ref points to, or an empty string if it does not exist. Refs are
+// either stored in their own file or in packed-refs. Refs specific to a worktree, such as HEAD, are in its git
+// directory, all others are in the common git directory.
+func resolveGitRef(gitDir, commonDir, ref string) string {
+	for _, dir := range []string{gitDir, commonDir} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref)))
+		if err != nil {
+			continue
+		}
+		value := strings.TrimSpace(string(data))
+		if strings.HasPrefix(value, "ref: ") {
+			return resolveGitRef(gitDir, commonDir, strings.TrimPrefix(value, "ref: "))
+		}
+		return value
+	}
+
+	f, err := os.Open(filepath.Join(commonDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		hash, name, ok := strings.Cut(scanner.Text(), " ")
+		if ok && name == ref {
+			return hash
+		}
+	}
+	return ""
+}
+
+// gitDirty returns true if any file tracked in the index of gitDir differs from the working tree at root. Files whose
+// size and modification time match the index are assumed to be unchanged, like git does, the contents of all other
+// files are hashed and compared with the index. Line ending conversion and other filters are not applied, so a file
+// that is converted when checked out is always reported as changed.
+func gitDirty(root, gitDir, commonDir string) (bool, error) {
+	if gitObjectFormat(commonDir) != "sha1" {
+		return false, fmt.Errorf("unsupported object format")
+	}
+
+	indexPath := filepath.Join(gitDir, "index")
+	indexStat, err := os.Stat(indexPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Nothing has been added to the index yet
+			return false, nil
+		}
+		return false, err
+	}
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return false, err
+	}
+	entries, err := parseGitIndex(data)
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		if entry.skip {
+			continue
+		}
+		if entry.stage != 0 || entry.unstaged {
+			return true, nil
+		}
+		changed, err := gitEntryChanged(root, entry, indexStat)
+		if err != nil {
+			return false, err
+		}
+		if changed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// gitObjectFormat returns the hash algorithm used by the repository, which is either "sha1" or "sha256"
+func gitObjectFormat(commonDir string) string {
+	f, err := os.Open(filepath.Join(commonDir, "config"))
+	if err != nil {
+		return "sha1"
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if ok && strings.EqualFold(strings.TrimSpace(key), "objectformat") {
+			return strings.ToLower(strings.TrimSpace(value))
+		}
+	}
+	return "sha1"
+}
+
+// gitEntryChanged returns true if the file of entry in the working tree differs from the index
+func gitEntryChanged(root string, entry gitIndexEntry, indexStat os.FileInfo) (bool, error) {
+	if entry.mode&gitModeTypeMask == gitModeGitlink {
+		// Submodules are repositories of their own
+		return false, nil
+	}
+
+	name := filepath.Join(root, filepath.FromSlash(entry.name))
+	stat, err := os.Lstat(name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	isSymlink := entry.mode&gitModeTypeMask == gitModeSymlink
+	if isSymlink != (stat.Mode()&os.ModeSymlink != 0) || (!isSymlink && !stat.Mode().IsRegular()) {
+		return true, nil
+	}
+	if uint32(stat.Size()) != entry.size {
+		return true, nil
+	}
+	// A file modified in the same second the index was written may have the same modification time as the index
+	// records, so its contents are always compared
+	mtime := stat.ModTime()
+	racy := !mtime.Before(indexStat.ModTime())
+	sameTime := uint32(mtime.Unix()) == entry.mtime
+	if entry.mtimeNs != 0 && uint32(mtime.Nanosecond()) != entry.mtimeNs {
+		sameTime = false
+	}
+	if sameTime && !racy {
+		return false, nil
+	}
+
+	var contents []byte
+	if isSymlink {
+		target, err := os.Readlink(name)
+		if err != nil {
+			return false, err
+		}
+		contents = []byte(filepath.ToSlash(target))
+	} else if contents, err = os.ReadFile(name); err != nil {
+		return false, err
+	}
+	hash := sha1.New()
+	fmt.Fprintf(hash, "blob %d\x00", len(contents))
+	hash.Write(contents)
+	return !bytes.Equal(hash.Sum(nil), entry.hash), nil
+}
+
+// parseGitIndex parses the entries of a git index file in version 2, 3 or 4 of the format. See
+// https://git-scm.com/docs/index-format.
+func parseGitIndex(data []byte) ([]gitIndexEntry, error) {
+	if len(data) < 12 || string(data[:4]) != "DIRC" {
+		return nil, fmt.Errorf("invalid index")
+	}
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported index version %d", version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+
+	const (
+		entryFixedSize  = 62
+		flagAssumeValid = 0x8000
+		flagExtended    = 0x4000
+		flagStageMask   = 0x3000
+		flagSkip        = 0x4000
+		flagIntentToAdd = 0x2000
+	)
+	errTruncated := errors.New("truncated index")
+
+	entries := make([]gitIndexEntry, 0, count)
+	offset := 12
+	previousName := ""
+	for i := uint32(0); i < count; i++ {
+		if len(data) < offset+entryFixedSize {
+			return nil, errTruncated
+		}
+		fixed := data[offset : offset+entryFixedSize]
+		entry := gitIndexEntry{
+			mtime:   binary.BigEndian.Uint32(fixed[8:12]),
+			mtimeNs: binary.BigEndian.Uint32(fixed[12:16]),
+			mode:    binary.BigEndian.Uint32(fixed[24:28]),
+			size:    binary.BigEndian.Uint32(fixed[36:40]),
+			hash:    fixed[40:60],
+		}
+		flags := binary.BigEndian.Uint16(fixed[60:62])
+		entry.stage = flags & flagStageMask
+		entry.skip = flags&flagAssumeValid != 0
+		start := offset
+		offset += entryFixedSize
+		if flags&flagExtended != 0 {
+			if version < 3 || len(data) < offset+2 {
+				return nil, errTruncated
+			}
+			extended := binary.BigEndian.Uint16(data[offset : offset+2])
+			entry.skip = entry.skip || extended&flagSkip != 0
+			entry.unstaged = extended&flagIntentToAdd != 0
+			offset += 2
+		}
+
+		if version == 4 {
+			// The name is stored as the number of bytes to remove from the end of the previous name, followed by
+			// the bytes to append to it
+			strip, n := gitIndexVarint(data[offset:])
+			if n == 0 || strip > uint64(len(previousName)) {
+				return nil, errTruncated
+			}
+			offset += n
+			end := bytes.IndexByte(data[offset:], 0)
+			if end < 0 {
+				return nil, errTruncated
+			}
+			entry.name = previousName[:len(previousName)-int(strip)] + string(data[offset:offset+end])
+			offset += end + 1
+		} else {
+			end := bytes.IndexByte(data[offset:], 0)
+			if end < 0 {
+				return nil, errTruncated
+			}
+			entry.name = string(data[offset : offset+end])
+			// Entries are padded with 1 to 8 NUL bytes to a multiple of 8 bytes
+			offset = start + (offset+end-start+8)/8*8
+		}
+		previousName = entry.name
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// gitIndexVarint decodes the variable length integer used for names in version 4 of the index format, returning the
+// value and the number of bytes read, or 0 bytes if data is truncated. Unlike the varints of encoding/binary, 1 is
+// added to the value for every continuation byte.
+func gitIndexVarint(data []byte) (uint64, int) {
+	var value uint64
+	for i, c := range data {
+		if i > 0 {
+			value++
+		}
+		value = value<<7 | uint64(c&0x7f)
+		if c&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
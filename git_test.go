@@ -0,0 +1,267 @@
+package snapshot
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// gitTestLongDir is longer than 127 bytes, so that names after it in a version 4 index strip more than fits in one byte
+var gitTestLongDir = strings.Repeat("long", 40)
+
+// gitCommand returns a command that runs git in dir without reading the configuration of the user or the system
+func gitCommand(dir string, args ...string) *exec.Cmd {
+	args = append([]string{"-c", "user.name=snapshot", "-c", "user.email=snapshot@example.com", "-c", "core.autocrlf=false"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull, "HOME="+dir)
+	return cmd
+}
+
+// runGit runs git in dir and returns its output
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, err := gitCommand(dir, args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err.Error(), output)
+	}
+	return string(output)
+}
+
+func writeGitTestFile(t *testing.T, root, name, contents string) {
+	t.Helper()
+	name = filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatalf("Error creating directory: %s", err.Error())
+	}
+	if err := os.WriteFile(name, []byte(contents), 0644); err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+}
+
+// newGitTestRepo creates a repository with a commit of a few files, including names that share long prefixes
+func newGitTestRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	runGit(t, root, "init", "-q", "-b", "main")
+	for _, name := range []string{
+		"a.txt",
+		"dir/b.txt",
+		"dir/c.txt",
+		gitTestLongDir + "/one.txt",
+		gitTestLongDir + "/two.txt",
+		"z.txt",
+	} {
+		writeGitTestFile(t, root, name, name+"\n")
+	}
+	if err := os.Symlink("a.txt", filepath.Join(root, "link")); err != nil {
+		t.Fatalf("Error creating symlink: %s", err.Error())
+	}
+	runGit(t, root, "add", "-A")
+	runGit(t, root, "commit", "-q", "-m", "initial")
+	return root
+}
+
+// gitTestConflict leaves a.txt with a merge conflict
+func gitTestConflict(t *testing.T, root string) {
+	runGit(t, root, "checkout", "-q", "-b", "other")
+	writeGitTestFile(t, root, "a.txt", "other\n")
+	runGit(t, root, "commit", "-q", "-am", "other")
+	runGit(t, root, "checkout", "-q", "main")
+	writeGitTestFile(t, root, "a.txt", "main\n")
+	runGit(t, root, "commit", "-q", "-am", "main")
+	if err := gitCommand(root, "merge", "-q", "other").Run(); err == nil {
+		t.Fatalf("Expected a merge conflict")
+	}
+}
+
+// gitLsFiles returns the entries of the index as listed by git, with their mode, hash, stage and name
+func gitLsFiles(t *testing.T, root string) []gitIndexEntry {
+	entries := []gitIndexEntry{}
+	for _, line := range strings.Split(strings.TrimSuffix(runGit(t, root, "ls-files", "-z", "--stage"), "\x00"), "\x00") {
+		info, name, _ := strings.Cut(line, "\t")
+		fields := strings.Fields(info)
+		mode, err := strconv.ParseUint(fields[0], 8, 32)
+		if err != nil {
+			t.Fatalf("Invalid mode %q", fields[0])
+		}
+		hash, err := hex.DecodeString(fields[1])
+		if err != nil {
+			t.Fatalf("Invalid hash %q", fields[1])
+		}
+		stage, err := strconv.ParseUint(fields[2], 10, 16)
+		if err != nil {
+			t.Fatalf("Invalid stage %q", fields[2])
+		}
+		entries = append(entries, gitIndexEntry{name: name, mode: uint32(mode), hash: hash, stage: uint16(stage) << 12})
+	}
+	return entries
+}
+
+func TestParseGitIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, root string)
+		version uint32
+		// skip and unstaged are the names of the entries expected to have these flags set
+		skip     []string
+		unstaged []string
+	}{
+		{
+			name: "version 2",
+			setup: func(t *testing.T, root string) {
+				runGit(t, root, "update-index", "--index-version", "2")
+			},
+			version: 2,
+		},
+		{
+			name: "version 3 skip-worktree",
+			setup: func(t *testing.T, root string) {
+				runGit(t, root, "update-index", "--skip-worktree", "dir/b.txt")
+			},
+			version: 3,
+			skip:    []string{"dir/b.txt"},
+		},
+		{
+			name: "version 3 intent to add",
+			setup: func(t *testing.T, root string) {
+				writeGitTestFile(t, root, "new.txt", "new\n")
+				runGit(t, root, "add", "-N", "new.txt")
+			},
+			version:  3,
+			unstaged: []string{"new.txt"},
+		},
+		{
+			name: "version 4",
+			setup: func(t *testing.T, root string) {
+				runGit(t, root, "update-index", "--index-version", "4")
+			},
+			version: 4,
+		},
+		{
+			name: "version 4 skip-worktree",
+			setup: func(t *testing.T, root string) {
+				runGit(t, root, "update-index", "--index-version", "4")
+				runGit(t, root, "update-index", "--skip-worktree", "dir/b.txt", gitTestLongDir+"/two.txt")
+			},
+			version: 4,
+			skip:    []string{"dir/b.txt", gitTestLongDir + "/two.txt"},
+		},
+		{
+			name: "version 4 assume unchanged",
+			setup: func(t *testing.T, root string) {
+				runGit(t, root, "update-index", "--index-version", "4")
+				runGit(t, root, "update-index", "--assume-unchanged", "a.txt")
+			},
+			version: 4,
+			skip:    []string{"a.txt"},
+		},
+		{
+			name:    "merge conflict",
+			setup:   gitTestConflict,
+			version: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root := newGitTestRepo(t)
+			test.setup(t, root)
+
+			data, err := os.ReadFile(filepath.Join(root, ".git", "index"))
+			if err != nil {
+				t.Fatalf("Error reading index: %s", err.Error())
+			}
+			if version := binary.BigEndian.Uint32(data[4:8]); version != test.version {
+				t.Fatalf("Expected git to write version %d of the index, got %d", test.version, version)
+			}
+			entries, err := parseGitIndex(data)
+			if err != nil {
+				t.Fatalf("Error parsing index: %s", err.Error())
+			}
+
+			expected := gitLsFiles(t, root)
+			if len(entries) != len(expected) {
+				t.Fatalf("Expected %d entries, got %d", len(expected), len(entries))
+			}
+			for i, entry := range entries {
+				want := expected[i]
+				if entry.name != want.name || entry.mode != want.mode || entry.stage != want.stage || string(entry.hash) != string(want.hash) {
+					t.Errorf("Entry %d: got %s %o %x %d, expected %s %o %x %d", i, entry.name, entry.mode, entry.hash, entry.stage, want.name, want.mode, want.hash, want.stage)
+				}
+				if skip := containsString(test.skip, entry.name); entry.skip != skip {
+					t.Errorf("Entry %s: expected skip %t, got %t", entry.name, skip, entry.skip)
+				}
+				if unstaged := containsString(test.unstaged, entry.name); entry.unstaged != unstaged {
+					t.Errorf("Entry %s: expected unstaged %t, got %t", entry.name, unstaged, entry.unstaged)
+				}
+			}
+		})
+	}
+}
+
+func TestParseGitIndexTruncated(t *testing.T) {
+	root := newGitTestRepo(t)
+	runGit(t, root, "update-index", "--index-version", "4")
+	data, err := os.ReadFile(filepath.Join(root, ".git", "index"))
+	if err != nil {
+		t.Fatalf("Error reading index: %s", err.Error())
+	}
+	entries, err := parseGitIndex(data)
+	if err != nil {
+		t.Fatalf("Error parsing index: %s", err.Error())
+	}
+
+	// The index is followed by extensions and a checksum, which are not read, so every index that is shorter than the
+	// entries must be rejected
+	end := -1
+	for i := 0; i <= len(data); i++ {
+		if _, err := parseGitIndex(data[:i]); err == nil {
+			end = i
+			break
+		}
+	}
+	if end <= 12 || end > len(data)-20 {
+		t.Fatalf("Unexpected end of the %d entries at %d of %d bytes", len(entries), end, len(data))
+	}
+}
+
+func TestReadGit(t *testing.T) {
+	root := newGitTestRepo(t)
+	runGit(t, root, "update-index", "--skip-worktree", "dir/b.txt")
+	head := strings.TrimSpace(runGit(t, root, "rev-parse", "HEAD"))
+
+	info := readGit(filepath.Join(root, "dir"))
+	if info == nil {
+		t.Fatalf("Expected a git repository")
+	}
+	if info.Root != root || info.Branch != "main" || info.Head != head || info.Dirty {
+		t.Errorf("Unexpected git info: %+v", *info)
+	}
+
+	// Changes to skipped files are ignored
+	writeGitTestFile(t, root, "dir/b.txt", "changed\n")
+	if info := readGit(root); info.Dirty {
+		t.Errorf("Expected a change to a skip-worktree file to be ignored")
+	}
+
+	writeGitTestFile(t, root, gitTestLongDir+"/one.txt", "changed\n")
+	if info := readGit(root); !info.Dirty {
+		t.Errorf("Expected the working tree to be dirty")
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package snapshot
+
+import (
+	"bufio"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GoroutineInfo describes a single goroutine
+type GoroutineInfo struct {
+	// ID is the goroutine ID
+	ID int `json:"id"`
+	// State is what the goroutine is doing, such as "running", "IO wait", or "chan receive"
+	State string `json:"state"`
+	// WaitDuration is roughly how long the goroutine has been blocked. The runtime only reports this in whole minutes
+	// and only once a goroutine has been blocked for at least one minute, otherwise it is zero.
+	WaitDuration time.Duration `json:"wait_duration"`
+	// LockedToThread is true if the goroutine is locked to an OS thread
+	LockedToThread bool `json:"locked_to_thread"`
+	// TopFunction is the function the goroutine is currently in
+	TopFunction string `json:"top_function"`
+}
+
+// GoroutineStates returns the number of goroutines in each state
+func (s Snapshot) GoroutineStates() map[string]int {
+	return countGoroutineStates(s.Goroutines)
+}
+
+func countGoroutineStates(goroutines []GoroutineInfo) map[string]int {
+	states := map[string]int{}
+	for _, g := range goroutines {
+		states[g.State]++
+	}
+	return states
+}
+
+// Stacks will write the full stack traces of all goroutines to w, in the same format as an unrecovered panic. This is
+// the quickest way to find out what every goroutine is doing during a hang or suspected deadlock, as it does not read
+// memory statistics or take a heap dump, either of which could block behind the same locks. Execution is paused very
+// briefly while the stacks are read.
+func Stacks(w io.Writer) error {
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// allStacks returns the stacks of all goroutines in the format of runtime.Stack
+func allStacks() string {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+// parseGoroutines parses a goroutine dump in the format of runtime.Stack or a panic
+func parseGoroutines(dump string) []GoroutineInfo {
+	stacks := parseGoroutineStacks(dump)
+	goroutines := make([]GoroutineInfo, len(stacks))
+	for i, stack := range stacks {
+		goroutines[i] = stack.GoroutineInfo
+		if len(stack.frames) > 0 {
+			goroutines[i].TopFunction = stack.frames[0].function
+		}
+	}
+	return goroutines
+}
+
+// goroutineStack is a goroutine along with the frames of its stack, innermost first
+type goroutineStack struct {
+	GoroutineInfo
+	frames []stackFrame
+	// count is the number of goroutines with this stack in a goroutine profile written at debug level 1
+	count int
+}
+
+// unknownGoroutineState is the state of goroutines parsed from a goroutine profile written at debug level 1, which
+// does not record the state of each goroutine
+const unknownGoroutineState = "unknown"
+
+type stackFrame struct {
+	// function is the name of the function, such as "main.(*T).Method"
+	function string
+	// location is the file and line of the frame, such as "/src/main.go:12"
+	location string
+}
+
+// parseGoroutineStacks parses a goroutine dump in the format of runtime.Stack or a panic, including every frame. It
+// also parses goroutine profiles written at debug level 1, the default for stack.txt in a full snapshot, where each
+// record is a number of goroutines with identical stacks. Those don't include the state of each goroutine, which is
+// unknownGoroutineState, and only have frames outside of the runtime. A goroutine is returned for each goroutine
+// counted in a record.
+func parseGoroutineStacks(dump string) []goroutineStack {
+	stacks := []goroutineStack{}
+	var current *goroutineStack
+	createdBy := false
+
+	scanner := bufio.NewScanner(strings.NewReader(dump))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if g, ok := parseGoroutineHeader(line); ok {
+			stacks = append(stacks, goroutineStack{GoroutineInfo: g})
+			current = &stacks[len(stacks)-1]
+			createdBy = false
+			continue
+		}
+		if count, ok := parseProfileRecord(line); ok {
+			stacks = append(stacks, goroutineStack{GoroutineInfo: GoroutineInfo{State: unknownGoroutineState}, count: count})
+			current = &stacks[len(stacks)-1]
+			createdBy = false
+			continue
+		}
+		if current == nil || createdBy || line == "" {
+			continue
+		}
+		if current.count > 0 {
+			if frame, ok := parseProfileFrame(line); ok {
+				current.frames = append(current.frames, frame)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			if n := len(current.frames); n > 0 && current.frames[n-1].location == "" {
+				location := strings.TrimSpace(line)
+				if i := strings.LastIndex(location, " +0x"); i > 0 {
+					location = location[:i]
+				}
+				current.frames[n-1].location = location
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "created by ") {
+			createdBy = true
+			continue
+		}
+		if strings.HasPrefix(line, "...") {
+			continue
+		}
+		current.frames = append(current.frames, stackFrame{function: functionName(line)})
+	}
+
+	expanded := make([]goroutineStack, 0, len(stacks))
+	for _, stack := range stacks {
+		expanded = append(expanded, stack)
+		for i := 1; i < stack.count; i++ {
+			expanded = append(expanded, stack)
+		}
+	}
+	return expanded
+}
+
+// parseProfileRecord parses the first line of a record in a goroutine profile written at debug level 1, like
+// "3 @ 0x43e0ce 0x44f0c5", returning the number of goroutines in the record
+func parseProfileRecord(line string) (int, bool) {
+	count, pcs, ok := strings.Cut(line, " @ ")
+	if !ok || !strings.HasPrefix(pcs, "0x") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// parseProfileFrame parses a frame of a record in a goroutine profile written at debug level 1, like
+// "#\t0x4cf090\tmain.worker+0xb0\t/src/main.go:12". Other lines starting with "#", such as labels, are ignored.
+func parseProfileFrame(line string) (stackFrame, bool) {
+	fields := strings.Fields(strings.TrimPrefix(line, "#"))
+	if !strings.HasPrefix(line, "#\t") || len(fields) < 3 || !strings.HasPrefix(fields[0], "0x") {
+		return stackFrame{}, false
+	}
+	function := fields[1]
+	if i := strings.LastIndex(function, "+0x"); i > 0 {
+		function = function[:i]
+	}
+	return stackFrame{function: function, location: fields[len(fields)-1]}, true
+}
+
+// parseGoroutineHeader parses a line like "goroutine 18 [chan receive, 5 minutes]:"
+func parseGoroutineHeader(line string) (GoroutineInfo, bool) {
+	g := GoroutineInfo{}
+	if !strings.HasPrefix(line, "goroutine ") || !strings.HasSuffix(line, "]:") {
+		return g, false
+	}
+	open := strings.Index(line, "[")
+	if open == -1 {
+		return g, false
+	}
+
+	fields := strings.Fields(line[:open])
+	if len(fields) < 2 {
+		return g, false
+	}
+	id, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return g, false
+	}
+	g.ID = id
+
+	for i, part := range strings.Split(line[open+1:len(line)-2], ", ") {
+		if i == 0 {
+			g.State = part
+			continue
+		}
+		if part == "locked to thread" {
+			g.LockedToThread = true
+			continue
+		}
+		if minutes, unit, ok := strings.Cut(part, " "); ok && strings.HasPrefix(unit, "minute") {
+			if n, err := strconv.Atoi(minutes); err == nil {
+				g.WaitDuration = time.Duration(n) * time.Minute
+			}
+		}
+	}
+
+	return g, true
+}
+
+// functionName returns the function from a stack frame line like "main.(*T).Method(0xc000010000, ...)"
+func functionName(line string) string {
+	if i := strings.LastIndex(line, "("); i > 0 && strings.HasSuffix(line, ")") {
+		return line[:i]
+	}
+	return line
+}
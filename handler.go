@@ -0,0 +1,52 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Handler returns an HTTP handler that responds with a full snapshot ZIP file, like Full.
+//
+// Warning: the snapshot contains the environment and the entire heap of your application, which may include secrets.
+// The handler does no authentication of its own, you must protect the route it is registered on. Every request will
+// temporarily suspend all execution of your application, use SetMinInterval to limit how often that can happen. Requests
+// within the minimum interval are answered with 429 Too Many Requests.
+func Handler() http.Handler {
+	return http.HandlerFunc(HandlerFunc)
+}
+
+// HandlerFunc is an HTTP handler function that responds with a full snapshot ZIP file. See Handler.
+func HandlerFunc(w http.ResponseWriter, r *http.Request) {
+	fileName := DefaultFileName()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+
+	cw := &countingWriter{w: w}
+	if err := FullTo(cw); err != nil {
+		if cw.n == 0 {
+			w.Header().Del("Content-Disposition")
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrTooSoon) {
+				status = http.StatusTooManyRequests
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		// Part of the ZIP has already been sent, abort the response so that the client doesn't see a complete file
+		panic(http.ErrAbortHandler)
+	}
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,142 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// HeapDiff describes how memory in use on the heap changed between two heap profiles, by the function that allocated
+// it. Heap profiles record where memory was allocated, not the type of the allocated objects.
+type HeapDiff struct {
+	// Functions are the allocating functions whose memory in use changed, sorted by the largest growth first
+	Functions []HeapDiffFunction
+	// InuseBytes is the total change in bytes in use
+	InuseBytes int64
+	// InuseObjects is the total change in objects in use
+	InuseObjects int64
+}
+
+// HeapDiffFunction describes how memory in use that was allocated by a function changed
+type HeapDiffFunction struct {
+	// Function is the name of the function that allocated the memory
+	Function string
+	// InuseBytes is the change in bytes in use
+	InuseBytes int64
+	// InuseObjects is the change in objects in use
+	InuseObjects int64
+}
+
+// DiffHeapProfiles will compare the heap profiles (heap.pprof) within two ZIP files written by Full, and return how the
+// memory in use changed from oldZip to newZip for each allocating function.
+//
+// The heap profile is sampled, by default about one allocation every 512 KiB is recorded, see runtime.MemProfileRate.
+// It also reflects the heap as of the most recently completed garbage collection, not the moment the snapshot was
+// taken.
+func DiffHeapProfiles(oldZip, newZip string) (HeapDiff, error) {
+	oldInuse, err := readHeapProfile(oldZip)
+	if err != nil {
+		return HeapDiff{}, err
+	}
+	newInuse, err := readHeapProfile(newZip)
+	if err != nil {
+		return HeapDiff{}, err
+	}
+
+	diff := HeapDiff{}
+	for function, usage := range newInuse {
+		old := oldInuse[function]
+		diff.Functions = append(diff.Functions, HeapDiffFunction{
+			Function:     function,
+			InuseBytes:   usage[0] - old[0],
+			InuseObjects: usage[1] - old[1],
+		})
+	}
+	for function, usage := range oldInuse {
+		if _, ok := newInuse[function]; ok {
+			continue
+		}
+		diff.Functions = append(diff.Functions, HeapDiffFunction{
+			Function:     function,
+			InuseBytes:   -usage[0],
+			InuseObjects: -usage[1],
+		})
+	}
+
+	functions := diff.Functions[:0]
+	for _, function := range diff.Functions {
+		if function.InuseBytes == 0 && function.InuseObjects == 0 {
+			continue
+		}
+		diff.InuseBytes += function.InuseBytes
+		diff.InuseObjects += function.InuseObjects
+		functions = append(functions, function)
+	}
+	diff.Functions = functions
+	sort.Slice(diff.Functions, func(i, j int) bool {
+		if diff.Functions[i].InuseBytes != diff.Functions[j].InuseBytes {
+			return diff.Functions[i].InuseBytes > diff.Functions[j].InuseBytes
+		}
+		return diff.Functions[i].Function < diff.Functions[j].Function
+	})
+	return diff, nil
+}
+
+// String returns a readable summary of the differences
+func (d HeapDiff) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "In use: %s, %+d objects\n", formatBytesDelta(d.InuseBytes), d.InuseObjects)
+	for _, function := range d.Functions {
+		fmt.Fprintf(b, "%14s %+10d objects  %s\n", formatBytesDelta(function.InuseBytes), function.InuseObjects, function.Function)
+	}
+	return b.String()
+}
+
+// readHeapProfile returns the bytes and objects in use for each allocating function in the heap profile within the ZIP
+// file at fileName
+func readHeapProfile(fileName string) (map[string][2]int64, error) {
+	zr, err := zip.OpenReader(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("zip: %s", err.Error())
+	}
+	defer zr.Close()
+
+	file := findArchiveFile(&zr.Reader, "heap.pprof")
+	if file == nil {
+		return nil, fmt.Errorf("zip: no heap.pprof in archive %s", fileName)
+	}
+	r, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("zip: %s", err.Error())
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("zip: %s", err.Error())
+	}
+
+	profile, err := parsePprof(data)
+	if err != nil {
+		return nil, err
+	}
+	inuseSpace := profile.sampleTypeIndex("inuse_space")
+	inuseObjects := profile.sampleTypeIndex("inuse_objects")
+	if inuseSpace == -1 || inuseObjects == -1 {
+		return nil, fmt.Errorf("pprof: %s is not a heap profile", fileName)
+	}
+
+	usage := map[string][2]int64{}
+	for _, sample := range profile.samples {
+		if len(sample.values) <= inuseSpace || len(sample.values) <= inuseObjects {
+			continue
+		}
+		function := profile.leafFunction(sample)
+		u := usage[function]
+		u[0] += sample.values[inuseSpace]
+		u[1] += sample.values[inuseObjects]
+		usage[function] = u
+	}
+	return usage, nil
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package snapshot
+
+import "os"
+
+// readIdentity sets the user and group running the process
+func readIdentity(s *Snapshot) {
+	s.Uid = os.Getuid()
+	s.Gid = os.Getgid()
+}
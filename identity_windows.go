@@ -0,0 +1,13 @@
+package snapshot
+
+import "os/user"
+
+// readIdentity sets the user running the process. Windows has no user or group IDs, so Uid and Gid are -1, which
+// leaves them out of JSON, and the username is used instead.
+func readIdentity(s *Snapshot) {
+	s.Uid = -1
+	s.Gid = -1
+	if u, err := user.Current(); err == nil {
+		s.Username = u.Username
+	}
+}
@@ -0,0 +1,36 @@
+package snapshot
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	intervalLock sync.Mutex
+	minInterval  time.Duration
+	lastFull     time.Time
+)
+
+// SetMinInterval sets the minimum time between the start of one full snapshot and the next. Full and all of its
+// variants, including Recorder, Handler and OnSignal, return ErrTooSoon without taking a snapshot if they are called
+// again within d. This stops a misbehaving caller, a flapping monitor, or repeated requests to Handler from suspending
+// your application over and over again. If d is zero, which is the default, there is no limit.
+func SetMinInterval(d time.Duration) {
+	intervalLock.Lock()
+	defer intervalLock.Unlock()
+	minInterval = d
+}
+
+// startFull returns ErrTooSoon if the previous full snapshot was started less than the minimum interval ago, otherwise
+// it records that a snapshot is starting now
+func startFull() error {
+	intervalLock.Lock()
+	defer intervalLock.Unlock()
+
+	now := time.Now()
+	if minInterval > 0 && !lastFull.IsZero() && now.Sub(lastFull) < minInterval {
+		return ErrTooSoon
+	}
+	lastFull = now
+	return nil
+}
@@ -0,0 +1,79 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+)
+
+// readGoroutineLabels returns the number of goroutines with each pprof label, keyed by "key=value". Goroutines inherit
+// the labels of the goroutine that started them, and pprof.Do sets labels for the duration of a function.
+func readGoroutineLabels() (map[string]int, error) {
+	buf := &bytes.Buffer{}
+	if err := pprof.Lookup("goroutine").WriteTo(buf, 1); err != nil {
+		return nil, err
+	}
+	return parseGoroutineLabels(buf.String()), nil
+}
+
+// parseGoroutineLabels parses a goroutine profile written with debug level 1, where goroutines with identical stacks
+// and labels are grouped into records like:
+//
+//	3 @ 0x43a8d6 0x44b1b2
+//	# labels: {"handler":"/upload"}
+//	#	0x43a8d5	runtime.gopark+0xd5	/usr/lib/go/src/runtime/proc.go:398
+func parseGoroutineLabels(profile string) map[string]int {
+	labels := map[string]int{}
+	count := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(profile))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if n, ok := parseProfileRecord(line); ok {
+			count = n
+			continue
+		}
+		if !strings.HasPrefix(line, "# labels: {") || !strings.HasSuffix(line, "}") {
+			continue
+		}
+		for _, label := range parseLabelSet(line[len("# labels: {") : len(line)-1]) {
+			labels[label] += count
+		}
+	}
+
+	return labels
+}
+
+// parseLabelSet parses a set of labels like `"a":"b", "c":"d"` into "a=b" and "c=d"
+func parseLabelSet(set string) []string {
+	labels := []string{}
+	for set != "" {
+		key, rest, ok := unquotePrefix(set)
+		if !ok || !strings.HasPrefix(rest, ":") {
+			break
+		}
+		value, rest, ok := unquotePrefix(rest[1:])
+		if !ok {
+			break
+		}
+		labels = append(labels, key+"="+value)
+		set = strings.TrimPrefix(rest, ", ")
+	}
+	return labels
+}
+
+// unquotePrefix unquotes the quoted string at the start of s and returns the rest of s
+func unquotePrefix(s string) (string, string, bool) {
+	quoted, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return "", "", false
+	}
+	unquoted, err := strconv.Unquote(quoted)
+	if err != nil {
+		return "", "", false
+	}
+	return unquoted, s[len(quoted):], true
+}
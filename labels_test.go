@@ -0,0 +1,95 @@
+package snapshot
+
+import (
+	"context"
+	"reflect"
+	"runtime/pprof"
+	"sync"
+	"testing"
+)
+
+func TestParseGoroutineLabels(t *testing.T) {
+	profile := `goroutine profile: total 9
+3 @ 0x43a8d6 0x44b1b2
+# labels: {"handler":"/upload"}
+#	0x43a8d5	runtime.gopark+0xd5	/usr/lib/go/src/runtime/proc.go:398
+
+2 @ 0x43a8d6 0x44b1b2 0x46d2c1
+# labels: {"handler":"/upload", "tenant":"a, b: c"}
+#	0x43a8d5	runtime.gopark+0xd5	/usr/lib/go/src/runtime/proc.go:398
+
+1 @ 0x43a8d6
+# labels: {"query":"{\"a\": 1, \"b\": \"x\"}", "url":"http://example.com:8080/a?b=c,d"}
+#	0x43a8d5	runtime.gopark+0xd5	/usr/lib/go/src/runtime/proc.go:398
+
+1 @ 0x43a8d6
+# labels: {"note":"1 @ 0x2"}
+
+2 @ 0x43a8d6 0x44b1b2
+#	0x43a8d5	runtime.gopark+0xd5	/usr/lib/go/src/runtime/proc.go:398
+`
+	expected := map[string]int{
+		"handler=/upload":                     5,
+		"tenant=a, b: c":                      2,
+		`query={"a": 1, "b": "x"}`:            1,
+		"url=http://example.com:8080/a?b=c,d": 1,
+		"note=1 @ 0x2":                        1,
+	}
+	if labels := parseGoroutineLabels(profile); !reflect.DeepEqual(labels, expected) {
+		t.Errorf("Expected %v, got %v", expected, labels)
+	}
+}
+
+func TestParseLabelSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		set      string
+		expected []string
+	}{
+		{"one", `"a":"b"`, []string{"a=b"}},
+		{"several", `"a":"b", "c":"d"`, []string{"a=b", "c=d"}},
+		{"commas and colons", `"k:1":"v, 2", "k, 3":"v:4"`, []string{"k:1=v, 2", "k, 3=v:4"}},
+		{"escaped quotes", `"a":"say \"hi\", bye"`, []string{`a=say "hi", bye`}},
+		{"empty value", `"a":""`, []string{"a="}},
+		{"empty", "", []string{}},
+		{"missing value", `"a":`, []string{}},
+		{"unquoted", `a:b`, []string{}},
+		{"stops at invalid", `"a":"b", c`, []string{"a=b"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if labels := parseLabelSet(test.set); !reflect.DeepEqual(labels, test.expected) {
+				t.Errorf("Expected %q, got %q", test.expected, labels)
+			}
+		})
+	}
+}
+
+func TestReadGoroutineLabels(t *testing.T) {
+	ch := make(chan struct{})
+	started := &sync.WaitGroup{}
+	stopped := &sync.WaitGroup{}
+	labels := pprof.Labels("handler", "/upload", "tenant", `a, "b": c`)
+	for i := 0; i < 3; i++ {
+		started.Add(1)
+		stopped.Add(1)
+		go pprof.Do(context.Background(), labels, func(context.Context) {
+			defer stopped.Done()
+			started.Done()
+			<-ch
+		})
+	}
+	started.Wait()
+	defer func() {
+		close(ch)
+		stopped.Wait()
+	}()
+
+	counts, err := readGoroutineLabels()
+	if err != nil {
+		t.Fatalf("Error reading goroutine labels: %s", err.Error())
+	}
+	if counts["handler=/upload"] != 3 || counts[`tenant=a, "b": c`] != 3 {
+		t.Errorf("Expected 3 goroutines with each label, got %v", counts)
+	}
+}
@@ -0,0 +1,33 @@
+package snapshot
+
+import "sync"
+
+var (
+	lastLock     sync.Mutex
+	lastSnapshot Snapshot
+	hasLast      bool
+)
+
+// SetLast records s as the most recent snapshot in the process, which is returned by Last. It is called automatically
+// each time a Monitor collects a snapshot and each time a full snapshot is taken, including by OnSignal, Handler and
+// CaptureOnPanic, with the snapshot written to snapshot.json. Call it yourself to include snapshots collected by other
+// means.
+func SetLast(s Snapshot) {
+	lastLock.Lock()
+	defer lastLock.Unlock()
+	lastSnapshot = s
+	hasLast = true
+}
+
+// Last returns the most recent snapshot in the process, see SetLast, and false if there hasn't been one yet. This lets
+// a debug endpoint show the latest state without a reference to where it was collected.
+//
+// The last snapshot is global state shared by everything in the process, including any libraries that take snapshots
+// or call SetLast. It is only as recent as the last snapshot anything took, which may be stale, and it keeps that
+// snapshot in memory for the life of the process. Keep a reference to your own snapshots, such as with a Monitor or a
+// Cache, if you need to know where they came from.
+func Last() (Snapshot, bool) {
+	lastLock.Lock()
+	defer lastLock.Unlock()
+	return lastSnapshot, hasLast
+}
@@ -0,0 +1,38 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func mappedLibraries() ([]string, error) {
+	data, err := os.ReadFile("/proc/self/maps")
+	if err != nil {
+		return nil, err
+	}
+	return parseMappedLibraries(string(data)), nil
+}
+
+// parseMappedLibraries returns the path of every shared object in a /proc/<pid>/maps file, in the order they are
+// mapped. Each line describes one mapping, such as:
+//
+//	7f2c8e600000-7f2c8e628000 r--p 00000000 08:01 1835053    /usr/lib/x86_64-linux-gnu/libc.so.6
+func parseMappedLibraries(maps string) []string {
+	libraries := []string{}
+	seen := map[string]bool{}
+	for _, line := range strings.Split(maps, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+		// The path may contain spaces, and is followed by " (deleted)" if the file was removed after it was mapped
+		path := strings.Join(fields[5:], " ")
+		if !strings.HasPrefix(path, "/") || !strings.Contains(filepath.Base(path), ".so") || seen[path] {
+			continue
+		}
+		seen[path] = true
+		libraries = append(libraries, path)
+	}
+	return libraries
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package snapshot
+
+func mappedLibraries() ([]string, error) {
+	return nil, nil
+}
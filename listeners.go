@@ -0,0 +1,64 @@
+package snapshot
+
+import (
+	"net"
+	"sync"
+)
+
+var (
+	listenersLock sync.Mutex
+	listeners     = map[string]net.Listener{}
+)
+
+// RegisterListener registers a listener of your application under name, such as "http" or "metrics", so that its
+// address is included in Snapshot.Listeners. Registering another listener with the same name replaces it.
+//
+// The returned listener is l, except that closing it also unregisters it. Use it in place of l, such as by passing it to
+// http.Serve, or call UnregisterListener once l is closed.
+func RegisterListener(name string, l net.Listener) net.Listener {
+	listenersLock.Lock()
+	defer listenersLock.Unlock()
+
+	registered := &registeredListener{Listener: l, name: name}
+	listeners[name] = registered
+	return registered
+}
+
+// UnregisterListener removes the listener registered under name, if any
+func UnregisterListener(name string) {
+	listenersLock.Lock()
+	defer listenersLock.Unlock()
+	delete(listeners, name)
+}
+
+// registeredListenerAddrs returns the address of every registered listener keyed by its name, or nil if there are
+// none
+func registeredListenerAddrs() map[string]string {
+	listenersLock.Lock()
+	defer listenersLock.Unlock()
+
+	if len(listeners) == 0 {
+		return nil
+	}
+	addrs := make(map[string]string, len(listeners))
+	for name, l := range listeners {
+		addrs[name] = l.Addr().String()
+	}
+	return addrs
+}
+
+// registeredListener is a listener that unregisters itself when it is closed
+type registeredListener struct {
+	net.Listener
+	name string
+}
+
+func (l *registeredListener) Close() error {
+	listenersLock.Lock()
+	// Only unregister if this listener wasn't replaced by another with the same name
+	if listeners[l.name] == l {
+		delete(listeners, l.name)
+	}
+	listenersLock.Unlock()
+	return l.Listener.Close()
+}
@@ -0,0 +1,86 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+// Load will read a snapshot from r, which contains the snapshot.json format written by Full.
+//
+// The format changes as fields are added to Snapshot, so Load is tolerant of snapshots written by other versions of this
+// package: unknown fields are ignored, missing fields are left zero, and so are fields whose type has changed. Check
+// SnapshotVersion to find out which version wrote the snapshot, it is empty for versions before 1.1.0.
+func Load(r io.Reader) (Snapshot, error) {
+	s := Snapshot{}
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return s, fmt.Errorf("decode: %s", err.Error())
+	}
+	return s, nil
+}
+
+// LoadStrict will read a snapshot from r like Load, but returns an error if it has any unknown fields or fields of the
+// wrong type. Use it in tests to check that snapshots written by one version of your application can be read by
+// another without losing information.
+func LoadStrict(r io.Reader) (Snapshot, error) {
+	s := Snapshot{}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return s, fmt.Errorf("read: %s", err.Error())
+	}
+	if err := decodeSnapshot(data, &s, true); err != nil {
+		return s, fmt.Errorf("decode: %s", err.Error())
+	}
+	return s, nil
+}
+
+// LoadFile will read a snapshot from the file at fileName, which can either be a snapshot.json file or a ZIP file written
+// by Full. If it's a ZIP file, the first snapshot.json file within it is read.
+func LoadFile(fileName string) (Snapshot, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("open: %s", err.Error())
+	}
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil || !bytes.Equal(magic, []byte("PK\x03\x04")) {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return Snapshot{}, fmt.Errorf("open: %s", err.Error())
+		}
+		return Load(f)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("open: %s", err.Error())
+	}
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("zip: %s", err.Error())
+	}
+	file := findArchiveFile(zr, "snapshot.json")
+	if file == nil {
+		return Snapshot{}, fmt.Errorf("zip: no snapshot.json in archive")
+	}
+	r, err := file.Open()
+	if err != nil {
+		return Snapshot{}, fmt.Errorf("zip: %s", err.Error())
+	}
+	defer r.Close()
+	return Load(r)
+}
+
+// findArchiveFile returns the first file in zr named name, in any directory, or nil
+func findArchiveFile(zr *zip.Reader, name string) *zip.File {
+	for _, file := range zr.File {
+		if path.Base(file.Name) == name {
+			return file
+		}
+	}
+	return nil
+}
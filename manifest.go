@@ -0,0 +1,134 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"path"
+	"time"
+)
+
+// Manifest describes the contents of a snapshot archive, and is included in it as manifest.json. It is written after
+// every other file in the snapshot, so that it can include their hashes.
+type Manifest struct {
+	// SnapshotVersion is the version of this package that wrote the archive, see Version
+	SnapshotVersion string `json:"snapshot_version"`
+	// Timestamp is when the snapshot was started
+	Timestamp time.Time `json:"timestamp"`
+	// Files are the files in the snapshot, in the order they were written
+	Files []ManifestFile `json:"files"`
+	// HeapDump is the heap dump, if it was written to a file next to the archive because Options.SeparateHeapDump was
+	// set. Its name is relative to the directory containing the archive.
+	HeapDump *ManifestFile `json:"heap_dump,omitempty"`
+}
+
+// ManifestFile describes a single file in a snapshot archive
+type ManifestFile struct {
+	// Name is the path of the file within the archive, including the prefix if any
+	Name string `json:"name"`
+	// Size is the uncompressed size of the file in bytes
+	Size int64 `json:"size"`
+	// SHA256 is the hex encoded SHA-256 hash of the uncompressed contents of the file
+	SHA256 string `json:"sha256"`
+}
+
+// archive is a container that the artifacts of a snapshot are written into, such as a *zip.Writer. Each call to Create
+// finishes the previous file.
+type archive interface {
+	Create(name string) (io.Writer, error)
+}
+
+// sizedArchive is an archive that can write a file more efficiently if its size is known before it is written, such
+// as a tar file, which records the size of each file before its contents. Exactly size bytes must be written.
+type sizedArchive interface {
+	archive
+	CreateSized(name string, size int64) (io.Writer, error)
+}
+
+// createSized creates a file of size bytes in a, using CreateSized if a supports it
+func createSized(a archive, name string, size int64) (io.Writer, error) {
+	if sized, ok := a.(sizedArchive); ok {
+		return sized.CreateSized(name, size)
+	}
+	return a.Create(name)
+}
+
+// manifestWriter is an archive that records the size and hash of every file created in it
+type manifestWriter struct {
+	archive   archive
+	timestamp time.Time
+	files     []ManifestFile
+	current   *hashingWriter
+}
+
+type hashingWriter struct {
+	w    io.Writer
+	hash hash.Hash
+	size int64
+}
+
+func (h *hashingWriter) Write(p []byte) (int, error) {
+	n, err := h.w.Write(p)
+	h.hash.Write(p[:n])
+	h.size += int64(n)
+	return n, err
+}
+
+func (m *manifestWriter) Create(name string) (io.Writer, error) {
+	m.finish()
+	return m.record(name)(m.archive.Create(name))
+}
+
+func (m *manifestWriter) CreateSized(name string, size int64) (io.Writer, error) {
+	m.finish()
+	return m.record(name)(createSized(m.archive, name, size))
+}
+
+// record returns a function that records the file created in the underlying archive, and hashes what is written to it
+func (m *manifestWriter) record(name string) func(w io.Writer, err error) (io.Writer, error) {
+	return func(w io.Writer, err error) (io.Writer, error) {
+		if err != nil {
+			return nil, err
+		}
+		m.files = append(m.files, ManifestFile{Name: name})
+		m.current = &hashingWriter{w: w, hash: sha256.New()}
+		return m.current, nil
+	}
+}
+
+// finish records the size and hash of the file currently being written, if any
+func (m *manifestWriter) finish() {
+	if m.current == nil {
+		return
+	}
+	file := &m.files[len(m.files)-1]
+	file.Size = m.current.size
+	file.SHA256 = hex.EncodeToString(m.current.hash.Sum(nil))
+	m.current = nil
+}
+
+// write writes manifest.json, which must be the last file in the snapshot
+func (m *manifestWriter) write(opts Options) error {
+	m.finish()
+	manifest := Manifest{
+		SnapshotVersion: Version,
+		Timestamp:       m.timestamp,
+		Files:           m.files,
+	}
+	if manifest.Files == nil {
+		manifest.Files = []ManifestFile{}
+	}
+	if opts.separateHeapDump != nil && opts.separateHeapDump.written {
+		manifest.HeapDump = &opts.separateHeapDump.file
+	}
+
+	manifestFile, err := m.archive.Create(path.Join(opts.Prefix, "manifest.json"))
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(manifestFile)
+	encoder.SetIndent("", opts.Indent)
+	return encoder.Encode(manifest)
+}
@@ -0,0 +1,62 @@
+package snapshot
+
+import (
+	"math"
+	"runtime/metrics"
+)
+
+// Histogram is the distribution of a runtime metric
+type Histogram struct {
+	// Counts is the number of samples in each bucket
+	Counts []uint64 `json:"counts"`
+	// Buckets are the boundaries of each bucket, there is always one more boundary than there are buckets. Bucket i
+	// contains samples between Buckets[i] (inclusive) and Buckets[i+1] (exclusive). Infinite boundaries are replaced
+	// with -math.MaxFloat64 or math.MaxFloat64, as they can't be represented in JSON.
+	Buckets []float64 `json:"buckets"`
+}
+
+// readMetrics returns the current value of every metric supported by the runtime, see runtime/metrics. Values are
+// either a uint64, a float64, or a Histogram.
+func readMetrics() map[string]any {
+	descriptions := metrics.All()
+	samples := make([]metrics.Sample, len(descriptions))
+	for i, description := range descriptions {
+		samples[i].Name = description.Name
+	}
+	metrics.Read(samples)
+
+	values := make(map[string]any, len(samples))
+	for _, sample := range samples {
+		switch sample.Value.Kind() {
+		case metrics.KindUint64:
+			values[sample.Name] = sample.Value.Uint64()
+		case metrics.KindFloat64:
+			values[sample.Name] = finiteFloat(sample.Value.Float64())
+		case metrics.KindFloat64Histogram:
+			histogram := sample.Value.Float64Histogram()
+			buckets := make([]float64, len(histogram.Buckets))
+			for i, bucket := range histogram.Buckets {
+				buckets[i] = finiteFloat(bucket)
+			}
+			values[sample.Name] = Histogram{
+				Counts:  append([]uint64{}, histogram.Counts...),
+				Buckets: buckets,
+			}
+		}
+	}
+	return values
+}
+
+// finiteFloat replaces infinite values with the largest finite value of the same sign, and NaN with zero
+func finiteFloat(f float64) float64 {
+	if math.IsInf(f, 1) {
+		return math.MaxFloat64
+	}
+	if math.IsInf(f, -1) {
+		return -math.MaxFloat64
+	}
+	if math.IsNaN(f) {
+		return 0
+	}
+	return f
+}
@@ -0,0 +1,107 @@
+package snapshot
+
+import (
+	"sync"
+	"time"
+)
+
+// Monitor periodically collects snapshots in the background and keeps the most recent ones in memory.
+type Monitor struct {
+	interval  time.Duration
+	snapshots []Snapshot
+	next      int
+	count     int
+	stop      chan struct{}
+	done      chan struct{}
+	lock      sync.Mutex
+}
+
+// NewMonitor will create a new monitor that collects a snapshot every interval once started, keeping at most the last
+// keep snapshots. interval must be positive, otherwise Start returns ErrInvalidInterval.
+func NewMonitor(interval time.Duration, keep int) *Monitor {
+	if keep < 1 {
+		keep = 1
+	}
+	return &Monitor{
+		interval:  interval,
+		snapshots: make([]Snapshot, keep),
+	}
+}
+
+// Start will start collecting snapshots in the background. The first snapshot is collected after one interval. Calling
+// Start on a running monitor does nothing. ErrInvalidInterval is returned without starting if the interval is not
+// positive.
+func (m *Monitor) Start() error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.interval <= 0 {
+		return ErrInvalidInterval
+	}
+	if m.stop != nil {
+		return nil
+	}
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	go m.run(m.stop, m.done)
+	return nil
+}
+
+// Stop will stop collecting snapshots and wait for the background goroutine to exit. Snapshots that were already
+// collected are kept. It is safe to call Stop multiple times, or on a monitor that was never started.
+func (m *Monitor) Stop() {
+	m.lock.Lock()
+	stop, done := m.stop, m.done
+	m.stop, m.done = nil, nil
+	m.lock.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// Snapshots returns the snapshots that were collected, oldest first.
+func (m *Monitor) Snapshots() []Snapshot {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	snapshots := make([]Snapshot, 0, m.count)
+	start := m.next - m.count
+	if start < 0 {
+		start += len(m.snapshots)
+	}
+	for i := 0; i < m.count; i++ {
+		snapshots = append(snapshots, m.snapshots[(start+i)%len(m.snapshots)])
+	}
+	return snapshots
+}
+
+func (m *Monitor) run(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.add(Collect())
+		}
+	}
+}
+
+func (m *Monitor) add(s Snapshot) {
+	SetLast(s)
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.snapshots[m.next] = s
+	m.next = (m.next + 1) % len(m.snapshots)
+	if m.count < len(m.snapshots) {
+		m.count++
+	}
+}
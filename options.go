@@ -0,0 +1,322 @@
+package snapshot
+
+import (
+	"compress/flate"
+	"fmt"
+	"path"
+	"runtime/pprof"
+	"strings"
+	"time"
+)
+
+// Options describes which artifacts are included in a full snapshot archive.
+//
+// The zero value of Options includes nothing. Use DefaultOptions to start with every artifact enabled and disable the
+// ones you don't need.
+//
+// Only the heap dump suspends all execution of your application for a significant amount of time. The other artifacts
+// pause execution either very briefly or not at all:
+//   - snapshot.json: paused briefly to read memory statistics and the stacks of all goroutines, like Collect
+//   - summary.json: not paused if snapshot.json is included, otherwise the same as snapshot.json
+//   - stack.txt: paused briefly at the start and end of reading the goroutine profile, or for the entire time at
+//     StackDebugLevel 2
+//   - caller-stack.txt: not paused
+//   - sched.txt: paused briefly to read the stacks of all goroutines
+//   - cpu.pprof, trace.out, block.pprof, mutex.pprof, manifest.json: not paused
+//   - heap.bin: paused for the entire duration of the heap dump
+type Options struct {
+	// IncludeSnapshotJSON controls if snapshot.json, statistics about the running application and environment, is
+	// included in the archive.
+	IncludeSnapshotJSON bool
+	// IncludeSummary controls if summary.json, the most important numbers of snapshot.json in a stable layout, is
+	// included in the archive. See Summary.
+	IncludeSummary bool
+	// IncludeStack controls if stack.txt, the stacks of all goroutines, is included in the archive.
+	IncludeStack bool
+	// IncludeCallerStack controls if caller-stack.txt, the stack of only the goroutine that took the snapshot, is
+	// included in the archive. This is the same as the Stack field of snapshot.json, and shows what led to the snapshot
+	// being taken, such as the request to Handler or the check that decided something was wrong.
+	IncludeCallerStack bool
+	// StackDebugLevel is the verbosity of stack.txt. Level 1 groups goroutines with identical stacks together and is
+	// the most compact, level 2 lists every goroutine with its full stack and state in the same format as an
+	// unrecovered panic, which is most useful for finding deadlocks. Zero is treated as 1. DefaultOptions uses 1.
+	StackDebugLevel int
+	// MaxStackBytes is the largest stack.txt that will be included in the archive. With tens of thousands of
+	// goroutines the stacks can be larger than the rest of the archive. If they are larger than this they are cut off,
+	// and a marker with the number of goroutines is added to the end of stack.txt. If zero, there is no limit.
+	MaxStackBytes int64
+	// IncludeSched controls if sched.txt, a readable summary of the state of the scheduler, is included in the archive.
+	// It shows the goroutines in each state and the scheduling latency, which reveal goroutines that are runnable but
+	// waiting for a P to run on.
+	IncludeSched bool
+	// IncludeHeapDump controls if heap.bin, a dump of the entire heap, is included in the archive. The heap dump format
+	// is not supported by any standard tooling, most users will want IncludeHeapProfile instead.
+	//
+	// Warning: this is the only option that will suspend all execution of your application (stop-the-world) for a
+	// significant amount of time, for the entire duration of the dump. The size of the dump is at most the amount of
+	// memory used by the go application.
+	IncludeHeapDump bool
+	// MaxHeapDumpBytes is the largest heap dump that will be included in the archive. The size of the dump isn't known
+	// until it is written, so it is skipped without being taken if the memory obtained from the OS by the go runtime,
+	// which is the largest the dump can be, exceeds this, or exceeds the space available in TempDir. A note is recorded
+	// in snapshot.json instead. This prevents a snapshot from filling the disk, but may skip a dump that would have fit.
+	// If the dump is still larger than this once written, it is discarded. If zero, there is no limit.
+	MaxHeapDumpBytes int64
+	// SeparateHeapDump controls if the heap dump is written to its own file next to the archive instead of into it, such
+	// as "snapshot.heap.bin" for "snapshot.zip". The archive stays small enough to transfer quickly, and the heap dump
+	// only needs to be fetched if it is needed. A note with the name of the file is recorded in snapshot.json, and its
+	// size and hash in the manifest. This is only used when the archive is saved to a file by FullWithOptions,
+	// FullContextWithOptions or FullTarGzWithOptions, all other functions include the heap dump in the archive.
+	SeparateHeapDump bool
+	// TempDir is the directory that temporary files are written to while the snapshot is taken, most importantly the
+	// heap dump, which is as large as the memory used by the go application. When the snapshot is saved to a file, the
+	// default is the directory of that file, which must have room for the snapshot anyway. Otherwise, or if no
+	// directory is known, the default directory for temporary files is used, see os.TempDir. That is often a small
+	// tmpfs in containers, set TempDir to a volume with enough free space if the heap dump fails with ENOSPC.
+	TempDir string
+	// IncludeManifest controls if manifest.json, a list of every other file in the archive with its size and SHA-256
+	// hash, is included in the archive. See Manifest.
+	IncludeManifest bool
+	// CPUProfileDuration is how long to sample a CPU profile for, which is included as cpu.pprof in the archive and can
+	// be opened with `go tool pprof`. If zero, no CPU profile is taken. Taking a snapshot will block for at least this
+	// long.
+	CPUProfileDuration time.Duration
+	// TraceDuration is how long to record an execution trace for, which is included as trace.out in the archive and can
+	// be opened with `go tool trace`. If zero, no trace is recorded. Taking a snapshot will block for at least this
+	// long, after the CPU profile if both are enabled.
+	//
+	// Tracing records every scheduler, GC, and syscall event, typically slowing the application by a few percent while
+	// it runs, and the trace grows by roughly a few MiB per second for a busy application.
+	TraceDuration time.Duration
+	// IncludeHeapProfile controls if heap.pprof, a sampled profile of heap allocations, is included in the archive. It
+	// can be opened with `go tool pprof heap.pprof` and compared with DiffHeapProfiles. Writing the profile takes a
+	// little time, but does not pause execution.
+	//
+	// The profile only samples allocations, at the rate recorded as MemProfileRate in snapshot.json. To record more
+	// allocations, set runtime.MemProfileRate as early as possible in your application, changing it before taking a
+	// snapshot has no effect on allocations that were already made.
+	IncludeHeapProfile bool
+	// IncludeBlockProfile controls if block.pprof, a profile of where goroutines block on synchronization primitives,
+	// is included in the archive. The block profile is only populated while the block profile rate is set, see
+	// EnableContentionProfiling.
+	IncludeBlockProfile bool
+	// IncludeMutexProfile controls if mutex.pprof, a profile of the holders of contended mutexes, is included in the
+	// archive. The mutex profile is only populated while the mutex profile fraction is set, see
+	// EnableContentionProfiling.
+	IncludeMutexProfile bool
+	// IncludeProfiles are the names of any other pprof profiles to include in the archive as "<name>.pprof", such as
+	// "allocs", "goroutine", or "threadcreate", or a custom profile created with pprof.NewProfile. See pprof.Profiles for
+	// the profiles that are available. Profiles that are already included by one of the other options are only written
+	// once.
+	IncludeProfiles []string
+	// CompressionLevel is the deflate compression level used for files in the archive, one of the compress/flate
+	// constants. flate.BestCompression produces the smallest archive at the cost of taking longer and using more CPU,
+	// flate.BestSpeed is the opposite. flate.NoCompression is useful when the contents are incompressible, such as some
+	// heap dumps. DefaultOptions uses flate.DefaultCompression.
+	CompressionLevel int
+	// FileCompressionLevels overrides CompressionLevel for individual files in the archive, keyed by their name without
+	// Prefix, such as "heap.bin" or the name of one of ExtraFiles. Files with a level of flate.NoCompression are stored
+	// without compression, which is much faster for large heap dumps that don't compress well while keeping the small
+	// text and JSON files compressed. This is not used for tar.gz archives, where the whole archive is compressed.
+	FileCompressionLevels map[string]int
+	// Indent is the indentation used for each level of nesting in snapshot.json. If empty, snapshot.json is compact and
+	// written on a single line, which is preferable for automated ingestion. DefaultOptions uses four spaces.
+	Indent string
+	// SkipEnviron controls if the environment is left out of snapshot.json entirely, see CollectOptions.
+	SkipEnviron bool
+	// RedactEnviron is an optional list of patterns matching the names of environment variables whose values are
+	// replaced with RedactedValue in snapshot.json. See CollectWithRedaction for how patterns are matched. If empty,
+	// no variables are redacted.
+	RedactEnviron []string
+	// Extra is any additional information to include in snapshot.json, see CollectWith.
+	Extra map[string]any
+	// IncludeGit controls if the state of the git repository containing the working directory is included in
+	// snapshot.json, see CollectOptions.
+	IncludeGit bool
+	// Now is an optional function that returns the time recorded in snapshot.json and manifest.json, see
+	// CollectOptions. If nil, time.Now is used.
+	Now func() time.Time
+	// ExtraFiles are files of your application to include in the archive, such as its configuration or logs. Keys are
+	// the path of the file within the archive, relative to Prefix, and values are the path of the file to copy. Files
+	// that can't be opened or aren't regular files, such as directories, are skipped and a note is recorded in
+	// snapshot.json instead. A file that can't be read to the end is left incomplete, with a note. Keys must be clean
+	// relative paths separated by "/", such as "logs/app.log", otherwise ErrInvalidPath is returned. Absolute paths and
+	// ".." are rejected so that extracting the archive can't write outside of the directory it is extracted into, and
+	// so are the names of the artifacts of the snapshot, such as "snapshot.json" or "heap.pprof".
+	ExtraFiles map[string]string
+	// Prefix is an optional directory name within the archive that all artifacts are placed in. If empty, artifacts
+	// are placed at the root of the archive. Like the keys of ExtraFiles, it must be a clean relative path, but may end
+	// with "/".
+	Prefix string
+	// OnProgress is an optional function called synchronously as each artifact is started, with its file name such as
+	// "snapshot.json" or "stack.txt". The heap dump reports "heap.bin-start" before the dump is taken, "heap.bin" when
+	// it begins to be copied into the archive, and "heap.bin-done" once it has been.
+	OnProgress func(stage string)
+
+	// separateHeapDump is the file that the heap dump is written to if SeparateHeapDump is set
+	separateHeapDump *separateHeapDump
+	// panicValue and panicStack are the panic recorded in snapshot.json by CaptureOnPanic
+	panicValue string
+	panicStack string
+}
+
+// CollectOptions describes how a snapshot is collected by CollectWithOptions. The zero value collects the same
+// snapshot as Collect.
+type CollectOptions struct {
+	// SkipEnviron controls if the environment is left out of the snapshot. If true, Environ is nil and the environment
+	// is never read. This is a stronger guarantee than RedactEnviron that no secrets from the environment are captured.
+	SkipEnviron bool
+	// RedactEnviron is an optional list of patterns matching the names of environment variables whose values are
+	// replaced with RedactedValue. See CollectWithRedaction for how patterns are matched.
+	RedactEnviron []string
+	// Extra is any additional information to include in the snapshot, see CollectWith.
+	Extra map[string]any
+	// IncludeGit controls if the HEAD commit and state of the git repository containing the working directory are
+	// included in the snapshot, see Snapshot.Git. The repository is read directly without running git. This is meant
+	// for development and CI, where the application runs from a checkout. Nothing is included if there is no repository.
+	IncludeGit bool
+	// Now is an optional function that returns the time the snapshot is collected at, which is recorded as Timestamp.
+	// Set it to a function returning a fixed time to get snapshots with a predictable timestamp in tests. If nil,
+	// time.Now is used. Uptime is always measured with the real time.
+	Now func() time.Time
+}
+
+// DefaultOptions returns the options used by Full, which includes every artifact.
+func DefaultOptions() Options {
+	return Options{
+		IncludeSnapshotJSON: true,
+		IncludeSummary:      true,
+		IncludeStack:        true,
+		IncludeCallerStack:  true,
+		IncludeSched:        true,
+		StackDebugLevel:     1,
+		IncludeHeapDump:     true,
+		IncludeHeapProfile:  true,
+		IncludeManifest:     true,
+		CompressionLevel:    flate.DefaultCompression,
+		Indent:              "    ",
+	}
+}
+
+// now returns the current time from o.Now, or time.Now if it is nil
+func (o CollectOptions) now() time.Time {
+	if o.Now != nil {
+		return o.Now()
+	}
+	return time.Now()
+}
+
+// withTempDir returns o with TempDir set to dir, unless TempDir is already set
+func (o Options) withTempDir(dir string) Options {
+	if o.TempDir == "" {
+		o.TempDir = dir
+	}
+	return o
+}
+
+// now returns the current time from o.Now, or time.Now if it is nil
+// collectOptions returns the options used to collect the snapshot written to snapshot.json and summary.json
+func (o Options) collectOptions() CollectOptions {
+	return CollectOptions{
+		SkipEnviron:   o.SkipEnviron,
+		RedactEnviron: o.RedactEnviron,
+		Extra:         o.Extra,
+		IncludeGit:    o.IncludeGit,
+		Now:           o.Now,
+	}
+}
+
+func (o Options) now() time.Time {
+	return CollectOptions{Now: o.Now}.now()
+}
+
+func (o Options) progress(stage string) {
+	if o.OnProgress != nil {
+		o.OnProgress(stage)
+	}
+}
+
+func (o Options) validate() error {
+	if o.CompressionLevel < flate.HuffmanOnly || o.CompressionLevel > flate.BestCompression {
+		return stageErr(ErrZip, fmt.Errorf("invalid compression level %d", o.CompressionLevel))
+	}
+	for name, level := range o.FileCompressionLevels {
+		if level < flate.HuffmanOnly || level > flate.BestCompression {
+			return stageErr(ErrZip, fmt.Errorf("invalid compression level %d for %s", level, name))
+		}
+	}
+	for _, name := range o.IncludeProfiles {
+		if pprof.Lookup(name) == nil {
+			return stageErr(ErrProfile, fmt.Errorf("unknown profile %q", name))
+		}
+	}
+	if o.StackDebugLevel < 0 || o.StackDebugLevel > 2 {
+		return stageErr(ErrStack, fmt.Errorf("invalid stack debug level %d", o.StackDebugLevel))
+	}
+	if o.Prefix != "" && !isArchivePath(strings.TrimSuffix(o.Prefix, "/")) {
+		return stageErr(ErrOpen, fmt.Errorf("%w: prefix %q", ErrInvalidPath, o.Prefix))
+	}
+	for name := range o.ExtraFiles {
+		if !isArchivePath(name) {
+			return stageErr(ErrExtraFiles, fmt.Errorf("%w: %q", ErrInvalidPath, name))
+		}
+		if isReservedArchivePath(name) {
+			return stageErr(ErrExtraFiles, fmt.Errorf("%w: %q is the name of an artifact of the snapshot", ErrInvalidPath, name))
+		}
+	}
+	return nil
+}
+
+// reservedArchiveNames are the names of the artifacts that a snapshot writes into its archive, other than profiles
+var reservedArchiveNames = []string{
+	"snapshot.json",
+	"summary.json",
+	"stack.txt",
+	"caller-stack.txt",
+	"sched.txt",
+	"trace.out",
+	"heap.bin",
+	"manifest.json",
+}
+
+// isReservedArchivePath returns true if name, or the directory it is in, has the name of an artifact of the snapshot,
+// or of any profile. Names are compared without regard to case, as FullDir may write to a case insensitive file
+// system.
+func isReservedArchivePath(name string) bool {
+	first, _, _ := strings.Cut(name, "/")
+	first = strings.ToLower(first)
+	if strings.HasSuffix(first, ".pprof") {
+		return true
+	}
+	for _, reserved := range reservedArchiveNames {
+		if first == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// isArchivePath returns true if name is a clean relative path within an archive that doesn't leave the directory the
+// archive is extracted into. Backslashes are rejected, as some tools treat them as separators.
+func isArchivePath(name string) bool {
+	if name == "" || path.IsAbs(name) || path.Clean(name) != name || strings.Contains(name, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// compressionLevel returns the compression level of the file with the given name in the archive, including Prefix
+func (o Options) compressionLevel(name string) int {
+	if o.Prefix != "" {
+		name = strings.TrimPrefix(name, path.Join(o.Prefix)+"/")
+	}
+	if level, ok := o.FileCompressionLevels[name]; ok {
+		return level
+	}
+	return o.CompressionLevel
+}
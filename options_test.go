@@ -0,0 +1,53 @@
+package snapshot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateExtraFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"config.json", true},
+		{"logs/app.log", true},
+		{"logs/snapshot.json", true},
+		{"stack.txt.bak", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../escape", false},
+		{"logs/../../escape", false},
+		{"./config.json", false},
+		{"logs//app.log", false},
+		{"logs\\app.log", false},
+		{"snapshot.json", false},
+		{"Snapshot.JSON", false},
+		{"summary.json", false},
+		{"stack.txt", false},
+		{"caller-stack.txt", false},
+		{"sched.txt", false},
+		{"trace.out", false},
+		{"heap.bin", false},
+		{"manifest.json", false},
+		{"heap.pprof", false},
+		{"goroutine.pprof", false},
+		{"stack.txt/app.log", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.ExtraFiles = map[string]string{test.name: "go.mod"}
+			err := opts.validate()
+			if test.valid {
+				if err != nil {
+					t.Errorf("Unexpected error: %s", err.Error())
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidPath) || !errors.Is(err, ErrExtraFiles) {
+				t.Errorf("Expected ErrInvalidPath, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+)
+
+// CollectWithPanic will take a snapshot, like Collect, that records a recovered panic in PanicValue and PanicStack. It
+// should be called from the deferred function that recovered the panic, as the stack is read when it is called and
+// only includes where the panic happened while the deferred function is running. If recovered is nil, the snapshot is
+// the same as Collect.
+//
+// Example:
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			s := snapshot.CollectWithPanic(r)
+//			// ...
+//		}
+//	}()
+func CollectWithPanic(recovered any) Snapshot {
+	value, stack := recoveredPanic(recovered)
+	s := Collect()
+	s.PanicValue = value
+	s.PanicStack = stack
+	return s
+}
+
+// CaptureOnPanic writes a full snapshot, like Full, to a new file in dir if the calling goroutine is panicking, and
+// then panics again with the same value so that the program still crashes as it would have. It must be deferred
+// directly, as recover only stops a panic when it is called by a deferred function. The panic value and the stack of
+// the panicking goroutine are recorded in snapshot.json, see CollectWithPanic. If the goroutine is not panicking, this
+// does nothing. Errors writing the snapshot are printed to stderr.
+//
+// The panic is raised again before the deferred call returns, so the stack printed when the program crashes still
+// includes where the panic happened. Execution is suspended while the heap dump is written, like Full.
+//
+// Example:
+//
+//	func main() {
+//		defer snapshot.CaptureOnPanic("/var/tmp")
+//		// ...
+//	}
+func CaptureOnPanic(dir string) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	opts := DefaultOptions()
+	opts.panicValue, opts.panicStack = recoveredPanic(recovered)
+	captureLock.Lock()
+	if err := FullWithOptions(filepath.Join(dir, DefaultFileName()), opts); err != nil {
+		fmt.Fprintf(os.Stderr, "snapshot: error writing snapshot on panic: %s\n", err.Error())
+	}
+	captureLock.Unlock()
+	panic(recovered)
+}
+
+// recoveredPanic returns the recovered panic value formatted with fmt and the stack of the calling goroutine, or empty
+// strings if recovered is nil
+func recoveredPanic(recovered any) (string, string) {
+	if recovered == nil {
+		return "", ""
+	}
+	return fmt.Sprint(recovered), string(debug.Stack())
+}
@@ -0,0 +1,135 @@
+package snapshot
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"time"
+)
+
+// Plan describes what a full snapshot would contain, see FullPlan
+type Plan struct {
+	// Artifacts are the files that would be written, in the order they would be written
+	Artifacts []PlannedArtifact
+	// EstimatedBytes is the sum of the estimated sizes of every artifact
+	EstimatedBytes int64
+	// StopsTheWorld is true if any of the artifacts would suspend all execution of your application for a significant
+	// amount of time
+	StopsTheWorld bool
+	// Duration is how long the snapshot would take at least, because of artifacts that are recorded over a period of
+	// time such as the CPU profile
+	Duration time.Duration
+}
+
+// PlannedArtifact is a file that would be written by a full snapshot
+type PlannedArtifact struct {
+	// Name is the name of the file within the archive, without Options.Prefix
+	Name string
+	// EstimatedBytes is a rough upper bound of the size of the file before compression
+	EstimatedBytes int64
+	// StopsTheWorld is true if writing the file would suspend all execution of your application for a significant
+	// amount of time. Only the heap dump does.
+	StopsTheWorld bool
+	// Duration is how long the file is recorded for, for the CPU profile and execution trace
+	Duration time.Duration
+	// Note explains anything unusual about the artifact, such as a heap dump that is likely to be skipped because it is
+	// larger than Options.MaxHeapDumpBytes
+	Note string
+}
+
+// FullPlan returns what a full snapshot with opts would contain and how large it would be, without taking the
+// snapshot. Sizes are rough estimates from the current number of goroutines and the memory used by the go runtime, like
+// EstimateFullSize. This does not pause execution, so it can be used to decide whether to take a snapshot at all.
+func FullPlan(opts Options) Plan {
+	goroutines := int64(runtime.NumGoroutine())
+	plan := Plan{}
+	add := func(artifact PlannedArtifact) {
+		plan.Artifacts = append(plan.Artifacts, artifact)
+		plan.EstimatedBytes += artifact.EstimatedBytes
+		plan.StopsTheWorld = plan.StopsTheWorld || artifact.StopsTheWorld
+		plan.Duration += artifact.Duration
+	}
+
+	// Estimates are generous, so that the plan is an upper bound for most applications
+	const profileBytes = 64 << 10
+	if opts.CPUProfileDuration > 0 {
+		add(PlannedArtifact{Name: "cpu.pprof", EstimatedBytes: profileBytes, Duration: opts.CPUProfileDuration})
+	}
+	if opts.TraceDuration > 0 {
+		// Traces grow quickly with the activity of the application
+		add(PlannedArtifact{Name: "trace.out", EstimatedBytes: int64(opts.TraceDuration.Seconds()+1) * (4 << 20), Duration: opts.TraceDuration})
+	}
+	extraNames := make([]string, 0, len(opts.ExtraFiles))
+	for name := range opts.ExtraFiles {
+		extraNames = append(extraNames, name)
+	}
+	sort.Strings(extraNames)
+	for _, name := range extraNames {
+		artifact := PlannedArtifact{Name: name}
+		if info, err := statExtraFile(opts.ExtraFiles[name]); err == nil {
+			artifact.EstimatedBytes = info.Size()
+		} else {
+			artifact.Note = "skipped: " + err.Error()
+		}
+		add(artifact)
+	}
+	if opts.IncludeSnapshotJSON {
+		add(PlannedArtifact{Name: "snapshot.json", EstimatedBytes: 128<<10 + goroutines*256})
+	}
+	if opts.IncludeSummary {
+		add(PlannedArtifact{Name: "summary.json", EstimatedBytes: 1 << 10})
+	}
+	if opts.IncludeStack {
+		stackBytes := goroutines * (2 << 10)
+		if opts.MaxStackBytes > 0 && stackBytes > opts.MaxStackBytes {
+			stackBytes = opts.MaxStackBytes
+		}
+		add(PlannedArtifact{Name: "stack.txt", EstimatedBytes: stackBytes})
+	}
+	if opts.IncludeCallerStack {
+		add(PlannedArtifact{Name: "caller-stack.txt", EstimatedBytes: 8 << 10})
+	}
+	if opts.IncludeSched {
+		add(PlannedArtifact{Name: "sched.txt", EstimatedBytes: 8 << 10})
+	}
+	included := map[string]bool{}
+	for _, profile := range []struct {
+		name    string
+		include bool
+	}{{"block", opts.IncludeBlockProfile}, {"mutex", opts.IncludeMutexProfile}, {"heap", opts.IncludeHeapProfile}} {
+		if profile.include {
+			included[profile.name] = true
+			add(PlannedArtifact{Name: profile.name + ".pprof", EstimatedBytes: profileBytes})
+		}
+	}
+	for _, name := range opts.IncludeProfiles {
+		if !included[name] {
+			included[name] = true
+			add(PlannedArtifact{Name: name + ".pprof", EstimatedBytes: profileBytes})
+		}
+	}
+	if opts.IncludeHeapDump {
+		artifact := PlannedArtifact{Name: "heap.bin", EstimatedBytes: runtimeMemory(), StopsTheWorld: true}
+		if opts.MaxHeapDumpBytes > 0 && artifact.EstimatedBytes > opts.MaxHeapDumpBytes {
+			artifact.Note = "skipped, the heap dump could be larger than MaxHeapDumpBytes"
+		} else if opts.SeparateHeapDump {
+			artifact.Note = "written to a separate file next to the archive"
+		}
+		add(artifact)
+	}
+	if opts.IncludeManifest {
+		add(PlannedArtifact{Name: "manifest.json", EstimatedBytes: int64(len(plan.Artifacts)+1) * 256})
+	}
+	return plan
+}
+
+// runtimeMemory returns the bytes of memory obtained from the OS by the go runtime, which is the largest a heap dump
+// can be
+func runtimeMemory() int64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/total:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() == metrics.KindUint64 {
+		return int64(sample[0].Value.Uint64())
+	}
+	return 0
+}
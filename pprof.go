@@ -0,0 +1,214 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// This file contains a minimal decoder for the pprof profile format, which is a gzip compressed protocol buffer
+// described in https://github.com/google/pprof/blob/main/proto/profile.proto. Only the parts needed to attribute
+// samples to functions are decoded.
+
+type pprofProfile struct {
+	sampleTypes []string
+	samples     []pprofSample
+	locations   map[uint64][]uint64 // location ID to function IDs, innermost first
+	functions   map[uint64]int64    // function ID to name string index
+	strings     []string
+}
+
+type pprofSample struct {
+	locationIDs []uint64
+	values      []int64
+}
+
+// leafFunction returns the name of the innermost function of the first location in the sample
+func (p *pprofProfile) leafFunction(sample pprofSample) string {
+	if len(sample.locationIDs) == 0 {
+		return ""
+	}
+	functionIDs := p.locations[sample.locationIDs[0]]
+	if len(functionIDs) == 0 {
+		return ""
+	}
+	return p.string(p.functions[functionIDs[0]])
+}
+
+func (p *pprofProfile) string(i int64) string {
+	if i < 0 || i >= int64(len(p.strings)) {
+		return ""
+	}
+	return p.strings[i]
+}
+
+// sampleTypeIndex returns the index of the named sample type in the values of each sample, or -1
+func (p *pprofProfile) sampleTypeIndex(name string) int {
+	for i, sampleType := range p.sampleTypes {
+		if sampleType == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// parsePprof decodes a profile, which may or may not be gzip compressed
+func parsePprof(data []byte) (*pprofProfile, error) {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		data, err = io.ReadAll(gz)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p := &pprofProfile{
+		locations: map[uint64][]uint64{},
+		functions: map[uint64]int64{},
+	}
+	sampleTypeIndexes := []int64{}
+	err := readProtobuf(data, func(field int, value uint64, message []byte) error {
+		switch field {
+		case 1: // sample_type
+			return readProtobuf(message, func(field int, value uint64, _ []byte) error {
+				if field == 1 {
+					sampleTypeIndexes = append(sampleTypeIndexes, int64(value))
+				}
+				return nil
+			})
+		case 2: // sample
+			sample := pprofSample{}
+			err := readProtobuf(message, func(field int, value uint64, packed []byte) error {
+				switch field {
+				case 1:
+					ids, err := readPackedVarints(value, packed)
+					sample.locationIDs = append(sample.locationIDs, ids...)
+					return err
+				case 2:
+					values, err := readPackedVarints(value, packed)
+					for _, v := range values {
+						sample.values = append(sample.values, int64(v))
+					}
+					return err
+				}
+				return nil
+			})
+			p.samples = append(p.samples, sample)
+			return err
+		case 4: // location
+			var id uint64
+			functionIDs := []uint64{}
+			err := readProtobuf(message, func(field int, value uint64, line []byte) error {
+				switch field {
+				case 1:
+					id = value
+				case 4:
+					return readProtobuf(line, func(field int, value uint64, _ []byte) error {
+						if field == 1 {
+							functionIDs = append(functionIDs, value)
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+			p.locations[id] = functionIDs
+			return err
+		case 5: // function
+			var id uint64
+			var name int64
+			err := readProtobuf(message, func(field int, value uint64, _ []byte) error {
+				switch field {
+				case 1:
+					id = value
+				case 2:
+					name = int64(value)
+				}
+				return nil
+			})
+			p.functions[id] = name
+			return err
+		case 6: // string_table
+			p.strings = append(p.strings, string(message))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range sampleTypeIndexes {
+		p.sampleTypes = append(p.sampleTypes, p.string(i))
+	}
+	return p, nil
+}
+
+// readProtobuf calls fn for each field in a protocol buffer message. For varint fields value is set, for length
+// delimited fields message is set. Fixed size fields are skipped.
+func readProtobuf(data []byte, fn func(field int, value uint64, message []byte) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("pprof: invalid field key")
+		}
+		data = data[n:]
+		field := int(key >> 3)
+
+		switch key & 7 {
+		case 0: // varint
+			value, n := binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("pprof: invalid varint")
+			}
+			data = data[n:]
+			if err := fn(field, value, nil); err != nil {
+				return err
+			}
+		case 1: // 64-bit
+			if len(data) < 8 {
+				return fmt.Errorf("pprof: truncated message")
+			}
+			data = data[8:]
+		case 2: // length delimited
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return fmt.Errorf("pprof: truncated message")
+			}
+			message := data[n : n+int(length)]
+			data = data[n+int(length):]
+			if err := fn(field, 0, message); err != nil {
+				return err
+			}
+		case 5: // 32-bit
+			if len(data) < 4 {
+				return fmt.Errorf("pprof: truncated message")
+			}
+			data = data[4:]
+		default:
+			return fmt.Errorf("pprof: unsupported wire type %d", key&7)
+		}
+	}
+	return nil
+}
+
+// readPackedVarints returns the values of a repeated varint field, which is either a single value or packed
+func readPackedVarints(value uint64, packed []byte) ([]uint64, error) {
+	if packed == nil {
+		return []uint64{value}, nil
+	}
+	values := []uint64{}
+	for len(packed) > 0 {
+		v, n := binary.Uvarint(packed)
+		if n <= 0 {
+			return nil, fmt.Errorf("pprof: invalid varint")
+		}
+		values = append(values, v)
+		packed = packed[n:]
+	}
+	return values, nil
+}
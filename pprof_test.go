@@ -0,0 +1,137 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+var pprofTestAllocations [][]byte
+
+//go:noinline
+func pprofTestAllocate() {
+	for i := 0; i < 100; i++ {
+		pprofTestAllocations = append(pprofTestAllocations, make([]byte, 64*1024))
+	}
+}
+
+// heapProfile returns a heap profile as written by WriteHeapProfile, which is gzip compressed
+func heapProfile(t *testing.T) []byte {
+	rate := runtime.MemProfileRate
+	runtime.MemProfileRate = 1
+	defer func() { runtime.MemProfileRate = rate }()
+	pprofTestAllocate()
+	t.Cleanup(func() { pprofTestAllocations = nil })
+	// The heap profile only includes allocations up to the most recently completed garbage collection
+	runtime.GC()
+
+	buf := &bytes.Buffer{}
+	if err := pprof.Lookup("heap").WriteTo(buf, 0); err != nil {
+		t.Fatalf("Error writing heap profile: %s", err.Error())
+	}
+	return buf.Bytes()
+}
+
+func gunzip(t *testing.T, data []byte) []byte {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Error decompressing: %s", err.Error())
+	}
+	data, err = io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("Error decompressing: %s", err.Error())
+	}
+	return data
+}
+
+func TestParsePprofHeap(t *testing.T) {
+	data := heapProfile(t)
+	for name, data := range map[string][]byte{"gzip": data, "uncompressed": gunzip(t, data)} {
+		t.Run(name, func(t *testing.T) {
+			profile, err := parsePprof(data)
+			if err != nil {
+				t.Fatalf("Error parsing heap profile: %s", err.Error())
+			}
+
+			expectedTypes := []string{"alloc_objects", "alloc_space", "inuse_objects", "inuse_space"}
+			if strings.Join(profile.sampleTypes, ",") != strings.Join(expectedTypes, ",") {
+				t.Errorf("Expected sample types %v, got %v", expectedTypes, profile.sampleTypes)
+			}
+			if len(profile.strings) == 0 || profile.strings[0] != "" {
+				t.Errorf("Expected the string table to start with an empty string")
+			}
+			inuseSpace := profile.sampleTypeIndex("inuse_space")
+			if inuseSpace < 0 {
+				t.Fatalf("inuse_space not found")
+			}
+
+			var allocated int64
+			for _, sample := range profile.samples {
+				if len(sample.values) != len(profile.sampleTypes) {
+					t.Fatalf("Expected %d values in each sample, got %d", len(profile.sampleTypes), len(sample.values))
+				}
+				if strings.HasSuffix(profile.leafFunction(sample), "snapshot.pprofTestAllocate") {
+					allocated += sample.values[inuseSpace]
+				}
+			}
+			if allocated < 100*64*1024 {
+				t.Errorf("Expected at least %d bytes in use by pprofTestAllocate, got %d", 100*64*1024, allocated)
+			}
+		})
+	}
+}
+
+func TestParsePprofTruncated(t *testing.T) {
+	compressed := heapProfile(t)
+	data := gunzip(t, compressed)
+
+	// Truncating the last field always leaves it incomplete, other lengths may end between two fields
+	for i := 0; i < len(data); i++ {
+		_, err := parsePprof(data[:i])
+		if i == len(data)-1 && err == nil {
+			t.Errorf("Expected an error parsing the profile without its last byte")
+		}
+	}
+	if _, err := parsePprof(compressed[:len(compressed)-1]); err == nil {
+		t.Errorf("Expected an error parsing a truncated gzip profile")
+	}
+}
+
+func TestParsePprofInvalid(t *testing.T) {
+	overlong := bytes.Repeat([]byte{0xff}, 10)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"overlong key", append(append([]byte{}, overlong...), 0x01)},
+		{"overflowing key", append(bytes.Repeat([]byte{0xff}, 9), 0x02)},
+		{"truncated key", []byte{0x80}},
+		{"overlong varint", append(append([]byte{0x08}, overlong...), 0x01)},
+		{"overflowing varint", append(append([]byte{0x08}, bytes.Repeat([]byte{0xff}, 9)...), 0x02)},
+		{"truncated varint", []byte{0x08, 0x80}},
+		{"overlong length", append(append([]byte{0x32}, overlong...), 0x01)},
+		{"truncated length", []byte{0x32, 0x80}},
+		{"length past the end", []byte{0x32, 0x05, 'a'}},
+		{"maximum length", append(append([]byte{0x32}, bytes.Repeat([]byte{0xff}, 9)...), 0x01)},
+		{"truncated 64-bit", []byte{0x09, 0, 0, 0}},
+		{"truncated 32-bit", []byte{0x0d, 0, 0}},
+		{"unsupported wire type", []byte{0x0b}},
+		{"overlong packed location", []byte{0x12, 0x0d, 0x0a, 0x0b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"truncated packed value", []byte{0x12, 0x03, 0x12, 0x01, 0x80}},
+		{"invalid sample type", []byte{0x0a, 0x02, 0x08, 0x80}},
+		{"invalid location line", []byte{0x22, 0x04, 0x22, 0x02, 0x08, 0x80}},
+		{"invalid function", []byte{0x2a, 0x02, 0x10, 0x80}},
+		{"invalid gzip", []byte{0x1f, 0x8b, 0x00}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := parsePprof(test.data); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
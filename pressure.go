@@ -0,0 +1,34 @@
+package snapshot
+
+// PressureInfo is how much time tasks spent stalled waiting for memory, CPU or IO, from the pressure stall information
+// (PSI) reported by Linux. Each resource is nil if the kernel does not report it, which is always the case on other
+// platforms.
+type PressureInfo struct {
+	// Source is "cgroup" if the stalls are those of the cgroup the process is in, or "system" if they are for the whole
+	// host because the cgroup does not report its own
+	Source string         `json:"source,omitempty"`
+	Memory *PressureStall `json:"memory,omitempty"`
+	CPU    *PressureStall `json:"cpu,omitempty"`
+	IO     *PressureStall `json:"io,omitempty"`
+}
+
+// PressureStall is the pressure stall information of a single resource. Memory pressure that stays high means the
+// kernel is thrashing, reclaiming and faulting pages back in on behalf of the process, well before it runs out of
+// memory.
+type PressureStall struct {
+	// Some is the time that at least one task was stalled on the resource
+	Some PressureAverages `json:"some"`
+	// Full is the time that all tasks were stalled on the resource at the same time. It is always zero for the CPU of
+	// the whole system.
+	Full PressureAverages `json:"full"`
+}
+
+// PressureAverages are the percentage of time tasks were stalled, averaged over the last 10 seconds, 60 seconds and
+// 300 seconds, and the total time stalled
+type PressureAverages struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	// TotalMicroseconds is the total time stalled, in microseconds
+	TotalMicroseconds uint64 `json:"total_us"`
+}
@@ -0,0 +1,82 @@
+package snapshot
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readPressure reads the pressure stall information of the cgroup the process is in, or of the whole system if the
+// cgroup does not report it. inContainer is true if the process was detected to be running in a container, see
+// cgroupDir.
+func readPressure(inContainer bool) PressureInfo {
+	info := PressureInfo{}
+	cgroups, _ := os.ReadFile("/proc/self/cgroup")
+	if path, ok := cgroupPaths(string(cgroups))[""]; ok {
+		dir := cgroupDir{root: "/sys/fs/cgroup", path: path, fallback: inContainer}
+		info.Memory = readPressureStall(dir.read("memory.pressure"))
+		info.CPU = readPressureStall(dir.read("cpu.pressure"))
+		info.IO = readPressureStall(dir.read("io.pressure"))
+	}
+	if info.Memory != nil || info.CPU != nil || info.IO != nil {
+		info.Source = "cgroup"
+		return info
+	}
+
+	info.Memory = readPressureStall(readProcPressure("memory"))
+	info.CPU = readPressureStall(readProcPressure("cpu"))
+	info.IO = readPressureStall(readProcPressure("io"))
+	if info.Memory != nil || info.CPU != nil || info.IO != nil {
+		info.Source = "system"
+	}
+	return info
+}
+
+func readProcPressure(resource string) (string, bool) {
+	data, err := os.ReadFile("/proc/pressure/" + resource)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// readPressureStall parses a pressure file, which looks like:
+//
+//	some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+//	full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+func readPressureStall(data string, ok bool) *PressureStall {
+	if !ok {
+		return nil
+	}
+
+	stall := &PressureStall{}
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var averages *PressureAverages
+		switch fields[0] {
+		case "some":
+			averages = &stall.Some
+		case "full":
+			averages = &stall.Full
+		default:
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "avg10":
+				averages.Avg10, _ = strconv.ParseFloat(value, 64)
+			case "avg60":
+				averages.Avg60, _ = strconv.ParseFloat(value, 64)
+			case "avg300":
+				averages.Avg300, _ = strconv.ParseFloat(value, 64)
+			case "total":
+				averages.TotalMicroseconds, _ = strconv.ParseUint(value, 10, 64)
+			}
+		}
+	}
+	return stall
+}
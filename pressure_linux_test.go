@@ -0,0 +1,69 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadPressureStall(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		ok       bool
+		expected *PressureStall
+	}{
+		{
+			name: "some and full",
+			data: "some avg10=1.52 avg60=0.87 avg300=0.25 total=123456789\nfull avg10=0.50 avg60=0.21 avg300=0.06 total=45678\n",
+			ok:   true,
+			expected: &PressureStall{
+				Some: PressureAverages{Avg10: 1.52, Avg60: 0.87, Avg300: 0.25, TotalMicroseconds: 123456789},
+				Full: PressureAverages{Avg10: 0.50, Avg60: 0.21, Avg300: 0.06, TotalMicroseconds: 45678},
+			},
+		},
+		{
+			// Older kernels only report some for the CPU
+			name: "only some",
+			data: "some avg10=0.00 avg60=0.03 avg300=0.01 total=2066186\n",
+			ok:   true,
+			expected: &PressureStall{
+				Some: PressureAverages{Avg60: 0.03, Avg300: 0.01, TotalMicroseconds: 2066186},
+			},
+		},
+		{
+			name: "full before some",
+			data: "full avg10=2.00 avg60=0.00 avg300=0.00 total=10\nsome avg10=4.00 avg60=0.00 avg300=0.00 total=20\n",
+			ok:   true,
+			expected: &PressureStall{
+				Some: PressureAverages{Avg10: 4, TotalMicroseconds: 20},
+				Full: PressureAverages{Avg10: 2, TotalMicroseconds: 10},
+			},
+		},
+		{
+			name: "unknown lines and fields",
+			data: "\nsome avg10=1.00 avg30=5.00 total=7\nother avg10=9.00\n",
+			ok:   true,
+			expected: &PressureStall{
+				Some: PressureAverages{Avg10: 1, TotalMicroseconds: 7},
+			},
+		},
+		{
+			name:     "empty",
+			data:     "",
+			ok:       true,
+			expected: &PressureStall{},
+		},
+		{
+			name:     "not reported",
+			ok:       false,
+			expected: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if stall := readPressureStall(test.data, test.ok); !reflect.DeepEqual(stall, test.expected) {
+				t.Errorf("Expected %+v, got %+v", test.expected, stall)
+			}
+		})
+	}
+}
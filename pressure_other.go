@@ -0,0 +1,7 @@
+//go:build !linux
+
+package snapshot
+
+func readPressure(inContainer bool) PressureInfo {
+	return PressureInfo{}
+}
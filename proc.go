@@ -0,0 +1,48 @@
+package snapshot
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrProcNotSupported is returned by CollectProc on platforms other than Linux
+var ErrProcNotSupported = errors.New("proc: collecting another process is only supported on Linux")
+
+// ProcSnapshot describes the operating system's view of a process, see CollectProc. Unlike Snapshot, it does not
+// include anything from the go runtime, as that can only be read from within the process itself.
+type ProcSnapshot struct {
+	// Timestamp is when the snapshot was collected
+	Timestamp time.Time `json:"timestamp"`
+	// Pid is the process ID
+	Pid int `json:"pid"`
+	// PPid is the ID of the parent process
+	PPid int `json:"ppid"`
+	// Name is the name of the command, which may be truncated
+	Name string `json:"name"`
+	// State is the state of the process, such as "S (sleeping)" or "D (disk sleep)"
+	State string `json:"state"`
+	// Cmdline are the command line arguments of the process, including the program
+	Cmdline []string `json:"cmdline"`
+	// Executable is the path to the executable of the process
+	Executable string `json:"executable,omitempty"`
+	// Wd is the working directory of the process
+	Wd string `json:"wd,omitempty"`
+	// Uid is the real user ID of the process
+	Uid int `json:"uid"`
+	// Gid is the real group ID of the process
+	Gid int `json:"gid"`
+	// NumThreads is the number of threads in the process
+	NumThreads int `json:"num_threads"`
+	// VmPeak is the peak virtual memory size in bytes
+	VmPeak uint64 `json:"vm_peak"`
+	// VmSize is the virtual memory size in bytes
+	VmSize uint64 `json:"vm_size"`
+	// VmHWM is the peak resident set size in bytes
+	VmHWM uint64 `json:"vm_hwm"`
+	// VmRSS is the resident set size in bytes
+	VmRSS uint64 `json:"vm_rss"`
+	// OpenFiles are the file descriptors open in the process
+	OpenFiles []OpenFile `json:"open_files,omitempty"`
+	// Connections are the TCP and UDP sockets open in the process
+	Connections []Connection `json:"connections,omitempty"`
+}
@@ -0,0 +1,119 @@
+package snapshot
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CollectProc will collect what the operating system reports about another process from /proc/<pid>, such as its
+// command line, memory usage, open files, and connections. This can be used to diagnose a process that is hung and
+// unable to snapshot itself. Reading most of this information requires running as the same user as the process, or
+// as root.
+//
+// Collection does not stop at the first error, the returned snapshot always contains all information that could be
+// collected even if an error is returned. If the process does not exist, an error is returned immediately. This is
+// only supported on Linux, on other platforms ErrProcNotSupported is returned.
+func CollectProc(pid int) (s ProcSnapshot, err error) {
+	setErr := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+
+	procDir := "/proc/" + strconv.Itoa(pid)
+	s.Timestamp = time.Now()
+	s.Pid = pid
+	if e := readProcStatus(procDir, &s); e != nil {
+		return s, fmt.Errorf("status: %s", e.Error())
+	}
+
+	if cmdline, e := os.ReadFile(procDir + "/cmdline"); e == nil {
+		s.Cmdline = strings.Split(strings.TrimSuffix(string(cmdline), "\x00"), "\x00")
+	} else {
+		setErr(fmt.Errorf("cmdline: %s", e.Error()))
+	}
+	if exe, e := os.Readlink(procDir + "/exe"); e == nil {
+		s.Executable = exe
+	} else {
+		setErr(fmt.Errorf("executable: %s", e.Error()))
+	}
+	if wd, e := os.Readlink(procDir + "/cwd"); e == nil {
+		s.Wd = wd
+	} else {
+		setErr(fmt.Errorf("wd: %s", e.Error()))
+	}
+	if files, e := readOpenFiles(procDir); e == nil {
+		s.OpenFiles = files
+		if conns, e := readConnections(procDir, files); e == nil {
+			s.Connections = conns
+		} else {
+			setErr(fmt.Errorf("connections: %s", e.Error()))
+		}
+	} else {
+		setErr(fmt.Errorf("open files: %s", e.Error()))
+	}
+
+	return
+}
+
+// readProcStatus reads the fields of /proc/<pid>/status that are in ProcSnapshot
+func readProcStatus(procDir string, s *ProcSnapshot) error {
+	f, err := os.Open(procDir + "/status")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch name {
+		case "Name":
+			s.Name = value
+		case "State":
+			s.State = value
+		case "PPid":
+			s.PPid, _ = strconv.Atoi(fields[0])
+		case "Uid":
+			s.Uid, _ = strconv.Atoi(fields[0])
+		case "Gid":
+			s.Gid, _ = strconv.Atoi(fields[0])
+		case "Threads":
+			s.NumThreads, _ = strconv.Atoi(fields[0])
+		case "VmPeak":
+			s.VmPeak = parseKB(fields)
+		case "VmSize":
+			s.VmSize = parseKB(fields)
+		case "VmHWM":
+			s.VmHWM = parseKB(fields)
+		case "VmRSS":
+			s.VmRSS = parseKB(fields)
+		}
+	}
+	return scanner.Err()
+}
+
+// parseKB parses a value like "1234 kB" into bytes
+func parseKB(fields []string) uint64 {
+	n, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	if len(fields) > 1 && fields[1] == "kB" {
+		n *= 1024
+	}
+	return n
+}
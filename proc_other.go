@@ -0,0 +1,9 @@
+//go:build !linux
+
+package snapshot
+
+// CollectProc will collect what the operating system reports about another process. This is only supported on Linux,
+// on other platforms ErrProcNotSupported is returned.
+func CollectProc(pid int) (ProcSnapshot, error) {
+	return ProcSnapshot{}, ErrProcNotSupported
+}
@@ -0,0 +1,29 @@
+package snapshot
+
+import "runtime"
+
+// EnableContentionProfiling sets the block profile rate and mutex profile fraction, so that the block and mutex
+// profiles are populated, and returns a function that restores the previous settings. See runtime.SetBlockProfileRate
+// and runtime.SetMutexProfileFraction for the meaning of blockRate and mutexFraction, a value of 1 records every event.
+//
+// Events are only recorded while profiling is enabled, so leave it enabled for a while before taking a snapshot:
+//
+//	restore := snapshot.EnableContentionProfiling(1, 1)
+//	time.Sleep(30 * time.Second)
+//	options := snapshot.DefaultOptions()
+//	options.IncludeBlockProfile = true
+//	options.IncludeMutexProfile = true
+//	err := snapshot.FullWithOptions("debug.zip", options)
+//	restore()
+//
+// The runtime has no way to read the current block profile rate, so restoring will always disable the block profile,
+// which is the default. Profiling every event has a measurable impact on performance.
+func EnableContentionProfiling(blockRate int, mutexFraction int) (restore func()) {
+	runtime.SetBlockProfileRate(blockRate)
+	previousMutexFraction := runtime.SetMutexProfileFraction(mutexFraction)
+
+	return func() {
+		runtime.SetBlockProfileRate(0)
+		runtime.SetMutexProfileFraction(previousMutexFraction)
+	}
+}
@@ -0,0 +1,48 @@
+package snapshot
+
+import (
+	"runtime"
+	"time"
+)
+
+// Rates describes how quickly memory was allocated and collected over a window of time, see CollectRate.
+type Rates struct {
+	// Window is how long the rates were measured over. If zero, rates were not measured.
+	Window time.Duration `json:"window"`
+	// AllocBytesPerSecond is the number of bytes allocated for heap objects per second
+	AllocBytesPerSecond float64 `json:"alloc_bytes_per_second"`
+	// MallocsPerSecond is the number of heap objects allocated per second
+	MallocsPerSecond float64 `json:"mallocs_per_second"`
+	// FreesPerSecond is the number of heap objects freed per second
+	FreesPerSecond float64 `json:"frees_per_second"`
+	// GCPerSecond is the number of completed garbage collection cycles per second
+	GCPerSecond float64 `json:"gc_per_second"`
+}
+
+// CollectRate will take a snapshot like Collect that also includes the rates at which memory was allocated and
+// collected over d. This catches allocation storms that a single snapshot would miss.
+//
+// CollectRate blocks for at least d. Execution is paused very briefly at the start of the window to read memory
+// statistics, and again at the end like Collect.
+func CollectRate(d time.Duration) Snapshot {
+	start := time.Now()
+	before := &runtime.MemStats{}
+	runtime.ReadMemStats(before)
+
+	time.Sleep(d)
+
+	s := Collect()
+	window := s.Timestamp.Sub(start)
+	seconds := window.Seconds()
+	if seconds <= 0 {
+		return s
+	}
+	s.Rates = Rates{
+		Window:              window,
+		AllocBytesPerSecond: float64(s.Memory.TotalAlloc-before.TotalAlloc) / seconds,
+		MallocsPerSecond:    float64(s.Memory.Mallocs-before.Mallocs) / seconds,
+		FreesPerSecond:      float64(s.Memory.Frees-before.Frees) / seconds,
+		GCPerSecond:         float64(s.Memory.NumGC-before.NumGC) / seconds,
+	}
+	return s
+}
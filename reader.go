@@ -0,0 +1,50 @@
+package snapshot
+
+import (
+	"context"
+	"io"
+)
+
+// FullReader will take a full detailed snapshot of your go application, like Full, and return a reader that the ZIP
+// file can be read from as it is written, such as to pass it to an uploader. The snapshot is taken in a background
+// goroutine that only writes as fast as the reader is read, any error taking it is returned by Read. The reader must
+// be closed, which stops the snapshot if it was not read to the end.
+//
+// Warning: this will temporarily suspend all execution of your application while the heap dump is written.
+func FullReader() (io.ReadCloser, error) {
+	return FullReaderWithOptions(DefaultOptions())
+}
+
+// FullReaderWithOptions will take a snapshot of your go application containing only the artifacts selected by opts,
+// and return a reader that the ZIP file can be read from, like FullReader.
+func FullReaderWithOptions(opts Options) (io.ReadCloser, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pw.CloseWithError(FullToContextWithOptions(ctx, pw, opts))
+	}()
+	return &snapshotReader{PipeReader: pr, cancel: cancel, done: done}, nil
+}
+
+// snapshotReader is the reading end of a snapshot being written in the background
+type snapshotReader struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Close stops the snapshot if it is still being written and waits for it to finish, which removes the temporary file
+// of the heap dump. A heap dump that is being taken can't be interrupted, so Close may block until it is done.
+func (s *snapshotReader) Close() error {
+	s.cancel()
+	// Closing the reader fails any write that is blocked waiting for it to be read
+	s.PipeReader.Close()
+	<-s.done
+	return nil
+}
@@ -0,0 +1,106 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+)
+
+// Recorder writes multiple snapshots over time into a single ZIP file. Each snapshot is placed in its own directory
+// within the archive, named with a sequence number and the time it was taken, such as
+// "snapshot-001-20060102T150405Z/".
+//
+// The archive is only complete once Close is called. Until then it is written to a temporary file next to the file it
+// was created for, which replaces that file when Close is called, so if the process exits before Close an existing file
+// is left as it was and the temporary file remains.
+type Recorder struct {
+	fileName string
+	f        *os.File
+	zw       *zipWriter
+	opts     Options
+	count    int
+	closed   bool
+	lock     sync.Mutex
+}
+
+// NewRecorder will create a new ZIP file at fileName to record full snapshots into. fileName must end with ".zip"
+func NewRecorder(fileName string) (*Recorder, error) {
+	return NewRecorderWithOptions(fileName, DefaultOptions())
+}
+
+// NewRecorderWithOptions will create a new ZIP file at fileName to record snapshots containing only the artifacts
+// selected by opts into. If opts.Prefix is set, each snapshot directory is placed within it.
+func NewRecorderWithOptions(fileName string, opts Options) (*Recorder, error) {
+	if err := checkFileName(fileName, ".zip"); err != nil {
+		return nil, err
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	f, err := createTempFile(fileName)
+	if err != nil {
+		return nil, stageErr(ErrOpen, err)
+	}
+
+	return &Recorder{
+		fileName: fileName,
+		f:        f,
+		zw:       newZipWriter(f, opts),
+		opts:     opts.withTempDir(filepath.Dir(fileName)),
+	}, nil
+}
+
+// Capture will take a snapshot and add it to the archive in a new directory. Captures are taken one at a time. A
+// capture that fails before anything was added to the archive, such as with ErrTooSoon, does not use up a sequence
+// number.
+//
+// Warning: if the heap dump is included, this will temporarily suspend all execution of your application.
+func (r *Recorder) Capture() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed {
+		return ErrRecorderClosed
+	}
+
+	sequence := r.count + 1
+	opts := r.opts
+	opts.Prefix = path.Join(r.opts.Prefix, fmt.Sprintf("snapshot-%03d-%s", sequence, r.opts.now().UTC().Format("20060102T150405Z")))
+	files := &fileRecorder{archive: &zipArchive{zw: r.zw, opts: opts}}
+	err := writeArchive(context.Background(), files, opts)
+	// The directory of a capture that failed part way is in the archive, so its number can't be used again
+	if err == nil || len(files.names) > 0 {
+		r.count = sequence
+	}
+	return err
+}
+
+// Close will finish writing the archive and close the file. Calling Close more than once does nothing.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	if err := r.zw.Close(); err != nil {
+		r.f.Close()
+		os.Remove(r.f.Name())
+		return stageErr(ErrZip, err)
+	}
+	if err := r.f.Close(); err != nil {
+		os.Remove(r.f.Name())
+		return stageErr(ErrClose, err)
+	}
+	if err := os.Rename(r.f.Name(), r.fileName); err != nil {
+		os.Remove(r.f.Name())
+		return stageErr(ErrClose, err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "debug.zip")
+	if err := os.WriteFile(fileName, []byte("previous"), 0644); err != nil {
+		t.Fatalf("Error writing file: %s", err.Error())
+	}
+
+	opts := DefaultOptions()
+	opts.IncludeHeapDump = false
+	recorder, err := NewRecorderWithOptions(fileName, opts)
+	if err != nil {
+		t.Fatalf("Error creating recorder: %s", err.Error())
+	}
+	if err := recorder.Capture(); err != nil {
+		t.Fatalf("Error capturing: %s", err.Error())
+	}
+
+	SetMinInterval(time.Hour)
+	err = recorder.Capture()
+	SetMinInterval(0)
+	if !errors.Is(err, ErrTooSoon) {
+		t.Fatalf("Expected ErrTooSoon, got %v", err)
+	}
+	if err := recorder.Capture(); err != nil {
+		t.Fatalf("Error capturing: %s", err.Error())
+	}
+
+	// The existing file is only replaced once the recorder is closed
+	if data, err := os.ReadFile(fileName); err != nil || string(data) != "previous" {
+		t.Errorf("Expected the existing file to be left as it was until Close, got %q %v", data, err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Error closing recorder: %s", err.Error())
+	}
+	if err := recorder.Capture(); !errors.Is(err, ErrRecorderClosed) {
+		t.Errorf("Expected ErrRecorderClosed, got %v", err)
+	}
+	if matches, _ := filepath.Glob(fileName + ".*.tmp"); len(matches) > 0 {
+		t.Errorf("Temporary files left behind: %v", matches)
+	}
+
+	zr, err := zip.OpenReader(fileName)
+	if err != nil {
+		t.Fatalf("Error opening archive: %s", err.Error())
+	}
+	defer zr.Close()
+	dirs := map[string]bool{}
+	for _, file := range zr.File {
+		dir, _, _ := strings.Cut(file.Name, "/")
+		dirs[dir[:len("snapshot-000")]] = true
+	}
+	names := []string{}
+	for dir := range dirs {
+		names = append(names, dir)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "snapshot-001,snapshot-002" {
+		t.Errorf("Expected snapshot-001 and snapshot-002 without a gap, got %v", names)
+	}
+}
@@ -0,0 +1,40 @@
+package snapshot
+
+import "strings"
+
+// RedactedValue replaces the value of any redacted environment variable.
+const RedactedValue = "***REDACTED***"
+
+// DefaultRedactPatterns are patterns matching the names of environment variables that commonly contain secrets.
+var DefaultRedactPatterns = []string{"SECRET", "TOKEN", "PASSWORD", "KEY"}
+
+// CollectWithRedaction will take a snapshot like Collect, but replaces the value of every environment variable whose
+// name matches any of patterns with RedactedValue. The name of the variable is kept. A name matches a pattern if it
+// contains the pattern, ignoring case. Pass DefaultRedactPatterns to redact commonly secret variables.
+func CollectWithRedaction(patterns []string) Snapshot {
+	s := Collect()
+	s.Environ = redactEnviron(s.Environ, patterns)
+	return s
+}
+
+func redactEnviron(environ []string, patterns []string) []string {
+	if len(patterns) == 0 {
+		return environ
+	}
+
+	redacted := make([]string, len(environ))
+	for i, env := range environ {
+		redacted[i] = env
+		name, _, ok := strings.Cut(env, "=")
+		if !ok {
+			continue
+		}
+		for _, pattern := range patterns {
+			if strings.Contains(strings.ToUpper(name), strings.ToUpper(pattern)) {
+				redacted[i] = name + "=" + RedactedValue
+				break
+			}
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,247 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// String returns a human readable report of the snapshot, see Report.
+func (s Snapshot) String() string {
+	b := &strings.Builder{}
+	s.Report(b)
+	return b.String()
+}
+
+// Report will write a human readable report of the snapshot to w, with sections for the process, memory, garbage
+// collector, goroutines, and build.
+func (s Snapshot) Report(w io.Writer) error {
+	b := &strings.Builder{}
+	section := func(title string) {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(title + "\n")
+	}
+	line := func(name string, format string, args ...any) {
+		fmt.Fprintf(b, "  %-16s "+format+"\n", append([]any{name + ":"}, args...)...)
+	}
+
+	section("Process")
+	line("Timestamp", "%s", s.Timestamp.Format(time.RFC3339))
+	if s.PanicValue != "" {
+		line("Panic", "%s", s.PanicValue)
+	}
+	line("Hostname", "%s", s.Hostname)
+	line("PID", "%d (parent %d)", s.Pid, s.PPid)
+	if !s.StartTime.IsZero() {
+		line("Started", "%s (up %s)", s.StartTime.Format(time.RFC3339), s.Uptime.Round(time.Second))
+	}
+	if s.Username != "" {
+		line("User", "%s", s.Username)
+	} else {
+		line("UID/GID", "%d/%d", s.Uid, s.Gid)
+	}
+	line("Executable", "%s", withRealPath(s.Executable, s.ExecutableReal))
+	line("Working Dir", "%s", withRealPath(s.Wd, s.WdReal))
+	line("CPUs", "%d (GOMAXPROCS %d)", s.CPU.NumCPU, s.CPU.GOMAXPROCS)
+	line("Load Average", "%.2f %.2f %.2f", s.CPU.Load1, s.CPU.Load5, s.CPU.Load15)
+	if s.Container.Detected {
+		memoryLimit, cpuQuota := "unlimited", "unlimited"
+		if s.Container.MemoryLimitBytes > 0 {
+			memoryLimit = formatBytes(uint64(s.Container.MemoryLimitBytes))
+		}
+		if s.Container.CPUQuota > 0 {
+			cpuQuota = fmt.Sprintf("%.2f CPUs", s.Container.CPUQuota)
+		}
+		line("Container", "%s (memory limit %s, CPU quota %s)", s.Container.Runtime, memoryLimit, cpuQuota)
+	}
+	for _, stall := range []struct {
+		name  string
+		stall *PressureStall
+	}{{"Memory Stall", s.Pressure.Memory}, {"CPU Stall", s.Pressure.CPU}, {"IO Stall", s.Pressure.IO}} {
+		if stall.stall != nil {
+			line(stall.name, "some %.2f%%, full %.2f%% (last 60s, %s)", stall.stall.Some.Avg60, stall.stall.Full.Avg60, s.Pressure.Source)
+		}
+	}
+	line("Disk", "%s free of %s", formatBytes(s.Disk.Available), formatBytes(s.Disk.Total))
+	switch {
+	case s.FDLimit.Soft == 0:
+		line("Open Files", "%d", s.NumFD)
+	case s.FDLimit.Soft == math.MaxUint64:
+		line("Open Files", "%d (unlimited)", s.NumFD)
+	default:
+		line("Open Files", "%d of %d (hard limit %s)", s.NumFD, s.FDLimit.Soft, fdLimitString(s.FDLimit.Hard))
+	}
+	line("Connections", "%d", len(s.Connections))
+	listenerNames := make([]string, 0, len(s.Listeners))
+	for name := range s.Listeners {
+		listenerNames = append(listenerNames, name)
+	}
+	sort.Strings(listenerNames)
+	for _, name := range listenerNames {
+		line("Listener", "%s %s", name, s.Listeners[name])
+	}
+
+	section("Memory")
+	line("Heap Alloc", "%s", formatBytes(s.Memory.HeapAlloc))
+	line("Heap In Use", "%s", formatBytes(s.Memory.HeapInuse))
+	line("Heap Idle", "%s", formatBytes(s.Memory.HeapIdle))
+	line("Heap Released", "%s", formatBytes(s.Memory.HeapReleased))
+	line("Heap Objects", "%d", s.Memory.HeapObjects)
+	line("Stack In Use", "%s", formatBytes(s.Memory.StackInuse))
+	line("Total Alloc", "%s", formatBytes(s.Memory.TotalAlloc))
+	line("Sys", "%s", formatBytes(s.Memory.Sys))
+	if s.Rates.Window > 0 {
+		line("Alloc Rate", "%s/s over %s", formatBytes(uint64(s.Rates.AllocBytesPerSecond)), s.Rates.Window.Round(time.Millisecond))
+		line("Malloc Rate", "%.0f/s (frees %.0f/s)", s.Rates.MallocsPerSecond, s.Rates.FreesPerSecond)
+		line("GC Rate", "%.2f/s", s.Rates.GCPerSecond)
+	}
+
+	section("Garbage Collector")
+	line("Cycles", "%d", s.GC.NumGC)
+	if s.GC.NumGC > 0 {
+		line("Last GC", "%s", s.GC.LastGC.Format(time.RFC3339))
+	}
+	line("Total Pause", "%s", s.GC.PauseTotal)
+	if len(s.GC.PauseQuantiles) == 5 && s.GC.NumGC > 0 {
+		q := s.GC.PauseQuantiles
+		line("Pauses", "min %s, p25 %s, p50 %s, p75 %s, max %s", q[0], q[1], q[2], q[3], q[4])
+	}
+	line("Next GC", "%s", formatBytes(s.Memory.NextGC))
+	if s.GCPercent < 0 {
+		line("GOGC", "off")
+	} else {
+		line("GOGC", "%d", s.GCPercent)
+	}
+	if s.MemoryLimit > 0 && s.MemoryLimit < math.MaxInt64 {
+		line("Memory Limit", "%s", formatBytes(uint64(s.MemoryLimit)))
+	} else {
+		line("Memory Limit", "none")
+	}
+	if s.MemoryPressure > 0 {
+		pressure := ""
+		if s.IsUnderMemoryPressure() {
+			pressure = " (under pressure)"
+		}
+		line("Memory Pressure", "%.0f%% of limit%s", s.MemoryPressure*100, pressure)
+	}
+	switch s.MemProfileRate {
+	case 0:
+		line("Profile Rate", "off")
+	case 1:
+		line("Profile Rate", "every allocation")
+	default:
+		line("Profile Rate", "every %s allocated", formatBytes(uint64(s.MemProfileRate)))
+	}
+
+	section("Goroutines")
+	line("Count", "%d", s.NumGoRoutines)
+	line("OS Threads", "%d", s.NumThreads)
+	line("Cgo Calls", "%d", s.NumCgoCall)
+	states := s.GoroutineStates()
+	stateNames := make([]string, 0, len(states))
+	for state := range states {
+		stateNames = append(stateNames, state)
+	}
+	sort.Slice(stateNames, func(i, j int) bool {
+		if states[stateNames[i]] != states[stateNames[j]] {
+			return states[stateNames[i]] > states[stateNames[j]]
+		}
+		return stateNames[i] < stateNames[j]
+	})
+	for _, state := range stateNames {
+		line(state, "%d", states[state])
+	}
+	labelNames := make([]string, 0, len(s.GoroutineLabels))
+	for label := range s.GoroutineLabels {
+		labelNames = append(labelNames, label)
+	}
+	sort.Slice(labelNames, func(i, j int) bool {
+		if s.GoroutineLabels[labelNames[i]] != s.GoroutineLabels[labelNames[j]] {
+			return s.GoroutineLabels[labelNames[i]] > s.GoroutineLabels[labelNames[j]]
+		}
+		return labelNames[i] < labelNames[j]
+	})
+	if len(labelNames) > 0 {
+		section("Goroutine Labels")
+	}
+	for _, label := range labelNames {
+		line(label, "%d", s.GoroutineLabels[label])
+	}
+
+	section("Build")
+	line("Go Version", "%s", s.GoVersion)
+	if s.VCSRevision != "" {
+		modified := ""
+		if s.VCSModified {
+			modified = " (modified)"
+		}
+		line("Revision", "%s %s%s", s.VCSRevision, s.VCSTime, modified)
+	}
+	if s.Git != nil {
+		dirty := ""
+		if s.Git.Dirty {
+			dirty = " (dirty)"
+		}
+		line("Git", "%s %s%s", s.Git.Branch, s.Git.Head, dirty)
+	}
+	line("Cgo Enabled", "%t", s.CgoEnabled)
+	line("Path", "%s", s.BuildInfo.Path)
+	line("Main Module", "%s %s", s.BuildInfo.Main.Path, s.BuildInfo.Main.Version)
+	line("Dependencies", "%d", len(s.BuildInfo.Deps))
+	for _, library := range s.MappedLibraries {
+		line("Library", "%s", library)
+	}
+
+	env := s.RuntimeEnv
+	if env.GOMAXPROCS != nil || env.GOGC != nil || env.GOMEMLIMIT != nil || len(env.GODEBUG) > 0 || env.GOTRACEBACK != "" {
+		section("Runtime Environment")
+	}
+	if env.GOMAXPROCS != nil {
+		line("GOMAXPROCS", "%d", *env.GOMAXPROCS)
+	}
+	if env.GOGC != nil && *env.GOGC < 0 {
+		line("GOGC", "off")
+	} else if env.GOGC != nil {
+		line("GOGC", "%d", *env.GOGC)
+	}
+	if env.GOMEMLIMIT != nil && *env.GOMEMLIMIT == math.MaxInt64 {
+		line("GOMEMLIMIT", "off")
+	} else if env.GOMEMLIMIT != nil {
+		line("GOMEMLIMIT", "%s", formatBytes(uint64(*env.GOMEMLIMIT)))
+	}
+	debugSettings := make([]string, 0, len(env.GODEBUG))
+	for key, value := range env.GODEBUG {
+		debugSettings = append(debugSettings, key+"="+value)
+	}
+	sort.Strings(debugSettings)
+	if len(debugSettings) > 0 {
+		line("GODEBUG", "%s", strings.Join(debugSettings, ","))
+	}
+	if env.GOTRACEBACK != "" {
+		line("GOTRACEBACK", "%s", env.GOTRACEBACK)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fdLimitString returns a file descriptor limit, or "unlimited"
+func fdLimitString(limit uint64) string {
+	if limit == math.MaxUint64 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d", limit)
+}
+
+// withRealPath returns path, followed by the path it resolves to if that is different
+func withRealPath(path, real string) string {
+	if real == "" || real == path {
+		return path
+	}
+	return path + " -> " + real
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !netbsd && !openbsd
+
+package snapshot
+
+func fdLimit() (FDLimit, error) {
+	return FDLimit{}, nil
+}
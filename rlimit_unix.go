@@ -0,0 +1,25 @@
+//go:build linux || darwin || freebsd || dragonfly || netbsd || openbsd
+
+package snapshot
+
+import (
+	"math"
+	"syscall"
+)
+
+func fdLimit() (FDLimit, error) {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return FDLimit{}, err
+	}
+	return FDLimit{Soft: rlimitValue(uint64(rlimit.Cur)), Hard: rlimitValue(uint64(rlimit.Max))}, nil
+}
+
+// rlimitValue returns math.MaxUint64 for RLIM_INFINITY, which is the largest value of a signed or unsigned 64 bit
+// integer depending on the platform
+func rlimitValue(value uint64) uint64 {
+	if value >= math.MaxInt64 {
+		return math.MaxUint64
+	}
+	return value
+}
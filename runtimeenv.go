@@ -0,0 +1,82 @@
+package snapshot
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RuntimeEnv are the environment variables that change the behavior of the go runtime, parsed from the environment.
+// Fields of variables that are not set, or whose value is invalid, are nil.
+type RuntimeEnv struct {
+	// GOMAXPROCS is the maximum number of CPUs that can execute go code at the same time
+	GOMAXPROCS *int `json:"gomaxprocs,omitempty"`
+	// GOGC is the garbage collection target percentage, or -1 if GOGC=off
+	GOGC *int `json:"gogc,omitempty"`
+	// GOMEMLIMIT is the soft memory limit in bytes, or math.MaxInt64 if GOMEMLIMIT=off
+	GOMEMLIMIT *int64 `json:"gomemlimit,omitempty"`
+	// GODEBUG are the settings in GODEBUG, such as "gctrace": "1"
+	GODEBUG map[string]string `json:"godebug,omitempty"`
+	// GOTRACEBACK is how much is printed when the application crashes, such as "all" or "crash"
+	GOTRACEBACK string `json:"gotraceback,omitempty"`
+}
+
+// readRuntimeEnv parses the environment variables that change the behavior of the go runtime
+func readRuntimeEnv() RuntimeEnv {
+	env := RuntimeEnv{}
+	if n, err := strconv.Atoi(os.Getenv("GOMAXPROCS")); err == nil && n > 0 {
+		env.GOMAXPROCS = &n
+	}
+	if value := os.Getenv("GOGC"); value == "off" {
+		off := -1
+		env.GOGC = &off
+	} else if n, err := strconv.Atoi(value); err == nil {
+		env.GOGC = &n
+	}
+	if limit, ok := parseMemoryLimit(os.Getenv("GOMEMLIMIT")); ok {
+		env.GOMEMLIMIT = &limit
+	}
+	if value := os.Getenv("GODEBUG"); value != "" {
+		env.GODEBUG = map[string]string{}
+		for _, setting := range strings.Split(value, ",") {
+			if key, value, ok := strings.Cut(setting, "="); ok && key != "" {
+				env.GODEBUG[key] = value
+			}
+		}
+	}
+	env.GOTRACEBACK = os.Getenv("GOTRACEBACK")
+	return env
+}
+
+// parseMemoryLimit parses a GOMEMLIMIT value, which is a number of bytes with an optional unit suffix such as "512MiB",
+// or "off"
+func parseMemoryLimit(value string) (int64, bool) {
+	if value == "off" {
+		return math.MaxInt64, true
+	}
+
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"KiB", 1 << 10},
+		{"MiB", 1 << 20},
+		{"GiB", 1 << 30},
+		{"TiB", 1 << 40},
+		{"B", 1},
+	}
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = value[:len(value)-len(unit.suffix)]
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/multiplier {
+		return 0, false
+	}
+	return n * multiplier, true
+}
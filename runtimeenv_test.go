@@ -0,0 +1,48 @@
+package snapshot
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseMemoryLimit(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+		valid    bool
+	}{
+		{"0", 0, true},
+		{"1048576", 1 << 20, true},
+		{"512B", 512, true},
+		{"64KiB", 64 << 10, true},
+		{"512MiB", 512 << 20, true},
+		{"4GiB", 4 << 30, true},
+		{"2TiB", 2 << 40, true},
+		{"off", math.MaxInt64, true},
+		{"9223372036854775807", math.MaxInt64, true},
+		{"8388607TiB", 8388607 << 40, true},
+		{"8388608TiB", 0, false},
+		{"", 0, false},
+		{"B", 0, false},
+		{"MiB", 0, false},
+		{"-1", 0, false},
+		{"-1MiB", 0, false},
+		{"1.5GiB", 0, false},
+		{"512MB", 0, false},
+		{"512mib", 0, false},
+		{"512 MiB", 0, false},
+		{"OFF", 0, false},
+		{"lots", 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			limit, ok := parseMemoryLimit(test.value)
+			if ok != test.valid {
+				t.Fatalf("Expected valid %t for %q, got %t", test.valid, test.value, ok)
+			}
+			if limit != test.expected {
+				t.Errorf("Expected %d for %q, got %d", test.expected, test.value, limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package snapshot
+
+import "path/filepath"
+
+// CollectSafe will take a snapshot like Collect, but leaves out all information that identifies the host or the
+// environment the application runs in, so that it can be shared publicly. All runtime, memory, and GC statistics are
+// kept.
+//
+// The following are left out entirely: Environ, Executable, ExecutableReal, Wd, WdReal, Hostname, Username, OpenFiles,
+// Connections, Listeners, and the path of Disk. Uid and Gid are set to -1. MappedLibraries are reduced to the names of
+// the files without their directories, such as "libc.so.6".
+//
+// Goroutine stacks are kept, and contain the paths to source files on the machine that built the application.
+func CollectSafe() Snapshot {
+	s := Collect()
+	removeHostInfo(&s)
+	return s
+}
+
+func removeHostInfo(s *Snapshot) {
+	s.Uid = -1
+	s.Gid = -1
+	s.Username = ""
+	s.Environ = nil
+	s.Executable = ""
+	s.ExecutableReal = ""
+	s.Wd = ""
+	s.WdReal = ""
+	s.Hostname = ""
+	s.OpenFiles = nil
+	s.Connections = nil
+	s.Listeners = nil
+	s.Disk.Path = ""
+	for i, library := range s.MappedLibraries {
+		s.MappedLibraries[i] = filepath.Base(library)
+	}
+}
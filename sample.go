@@ -0,0 +1,73 @@
+package snapshot
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// SampleResult are snapshots taken by Sample, along with statistics about them
+type SampleResult struct {
+	// Snapshots are the snapshots that were taken, oldest first
+	Snapshots []Snapshot
+	// Goroutines are statistics about the number of goroutines
+	Goroutines SampleStats
+	// HeapAlloc are statistics about the bytes of allocated heap objects
+	HeapAlloc SampleStats
+}
+
+// SampleStats summarize a value across a number of snapshots
+type SampleStats struct {
+	Mean float64
+	Min  float64
+	Max  float64
+	// P99 is the 99th percentile, the lowest value that is greater than or equal to 99% of values. With fewer than 100
+	// snapshots this is the same as Max.
+	P99 float64
+}
+
+// Sample will take n snapshots, like Collect, waiting interval between each of them, and summarize them. This is meant
+// for short measurements, such as during a load test, use Monitor and Series to watch trends over a long period. Each
+// snapshot pauses execution briefly, see Collect. Sample blocks until every snapshot has been taken, which takes
+// (n-1)*interval. If n is less than one, a single snapshot is taken.
+func Sample(n int, interval time.Duration) SampleResult {
+	if n < 1 {
+		n = 1
+	}
+
+	result := SampleResult{Snapshots: make([]Snapshot, 0, n)}
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		result.Snapshots = append(result.Snapshots, Collect())
+	}
+
+	goroutines := make([]float64, n)
+	heap := make([]float64, n)
+	for i, s := range result.Snapshots {
+		goroutines[i] = float64(s.NumGoRoutines)
+		heap[i] = float64(s.Memory.HeapAlloc)
+	}
+	result.Goroutines = sampleStats(goroutines)
+	result.HeapAlloc = sampleStats(heap)
+	return result
+}
+
+func sampleStats(values []float64) SampleStats {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, value := range sorted {
+		sum += value
+	}
+	// The nearest rank, so that the percentile is always one of the values
+	rank := int(math.Ceil(0.99*float64(len(sorted)))) - 1
+	return SampleStats{
+		Mean: sum / float64(len(sorted)),
+		Min:  sorted[0],
+		Max:  sorted[len(sorted)-1],
+		P99:  sorted[rank],
+	}
+}
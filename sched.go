@@ -0,0 +1,111 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// writeSched writes a readable summary of the state of the scheduler, similar to what GODEBUG=schedtrace prints, from
+// the /sched/ metrics of runtime/metrics and the states of all goroutines. The runtime does not report which goroutines
+// are queued on each P, but a large number of runnable goroutines or a high scheduling latency means that goroutines
+// are waiting for a P to run on. Reading the goroutine states pauses execution briefly.
+func writeSched(w io.Writer) error {
+	values := readMetrics()
+	names := []string{}
+	for name := range values {
+		if strings.HasPrefix(name, "/sched/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "GOMAXPROCS:\t%d\n", runtime.GOMAXPROCS(0))
+	fmt.Fprintf(tw, "CPUs:\t%d\n", runtime.NumCPU())
+	fmt.Fprintf(tw, "OS Threads:\t%d\n", numThreads())
+	fmt.Fprintf(tw, "Goroutines:\t%d\n", runtime.NumGoroutine())
+
+	fmt.Fprintf(tw, "\nGoroutine States\n")
+	states := countGoroutineStates(parseGoroutines(allStacks()))
+	stateNames := make([]string, 0, len(states))
+	for state := range states {
+		stateNames = append(stateNames, state)
+	}
+	sort.Slice(stateNames, func(i, j int) bool {
+		if states[stateNames[i]] != states[stateNames[j]] {
+			return states[stateNames[i]] > states[stateNames[j]]
+		}
+		return stateNames[i] < stateNames[j]
+	})
+	for _, state := range stateNames {
+		fmt.Fprintf(tw, "  %s:\t%d\n", state, states[state])
+	}
+
+	fmt.Fprintf(tw, "\nMetrics\n")
+	for _, name := range names {
+		switch value := values[name].(type) {
+		case uint64:
+			fmt.Fprintf(tw, "  %s\t%d\n", name, value)
+		case float64:
+			fmt.Fprintf(tw, "  %s\t%g\n", name, value)
+		}
+	}
+	for _, name := range names {
+		histogram, ok := values[name].(Histogram)
+		if !ok || !strings.HasSuffix(name, ":seconds") {
+			continue
+		}
+		fmt.Fprintf(tw, "\n%s\n", name)
+		fmt.Fprintf(tw, "  samples:\t%d\n", histogram.total())
+		if histogram.total() == 0 {
+			continue
+		}
+		for _, q := range []float64{0.5, 0.9, 0.99, 1} {
+			label := fmt.Sprintf("p%g", q*100)
+			if q == 1 {
+				label = "max"
+			}
+			fmt.Fprintf(tw, "  %s:\t<= %s\n", label, secondsDuration(histogram.quantile(q)))
+		}
+	}
+	return tw.Flush()
+}
+
+// total returns the number of samples in the histogram
+func (h Histogram) total() uint64 {
+	var total uint64
+	for _, count := range h.Counts {
+		total += count
+	}
+	return total
+}
+
+// quantile returns the upper boundary of the bucket containing the q quantile of samples, or its lower boundary if the
+// bucket has no upper boundary
+func (h Histogram) quantile(q float64) float64 {
+	total := h.total()
+	threshold := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i, count := range h.Counts {
+		seen += count
+		if count == 0 || seen < threshold {
+			continue
+		}
+		if h.Buckets[i+1] == math.MaxFloat64 {
+			return h.Buckets[i]
+		}
+		return h.Buckets[i+1]
+	}
+	return 0
+}
+
+// secondsDuration converts a number of seconds into a duration
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,134 @@
+package snapshot
+
+import (
+	"sort"
+)
+
+const (
+	// seriesWindows is the number of windows that IsLeaking divides a series into
+	seriesWindows = 3
+	// seriesMinGrowth is how much IsLeaking requires the lowest value to grow by from the first window to the last
+	seriesMinGrowth = 0.1
+)
+
+// Series is a sequence of snapshots taken over time, such as the snapshots kept by a Monitor, for analyzing trends in
+// memory and goroutine usage. Snapshots must be taken with Collect or one of its variants, CollectLight does not
+// include memory statistics. The zero value is an empty series. A Series is not safe for concurrent use.
+type Series struct {
+	snapshots []Snapshot
+}
+
+// Series returns a series of the snapshots the monitor has collected so far
+func (m *Monitor) Series() *Series {
+	return &Series{snapshots: m.Snapshots()}
+}
+
+// Add adds a snapshot to the series. Snapshots are kept in order of their timestamp, regardless of the order they are
+// added in.
+func (s *Series) Add(sn Snapshot) {
+	i := sort.Search(len(s.snapshots), func(i int) bool {
+		return s.snapshots[i].Timestamp.After(sn.Timestamp)
+	})
+	s.snapshots = append(s.snapshots, Snapshot{})
+	copy(s.snapshots[i+1:], s.snapshots[i:])
+	s.snapshots[i] = sn
+}
+
+// Len returns the number of snapshots in the series
+func (s *Series) Len() int {
+	return len(s.snapshots)
+}
+
+// Snapshots returns the snapshots in the series, oldest first
+func (s *Series) Snapshots() []Snapshot {
+	return append([]Snapshot{}, s.snapshots...)
+}
+
+// HeapGrowthRate returns how quickly the heap grew over the series in bytes per second, which is negative if it shrank.
+// This is the slope of the line that best fits the allocated heap (Memory.HeapAlloc) of every snapshot, so a single
+// snapshot taken just before or after a garbage collection has little effect. Zero is returned if the series has
+// fewer than two snapshots.
+func (s *Series) HeapGrowthRate() float64 {
+	if len(s.snapshots) < 2 {
+		return 0
+	}
+
+	start := s.snapshots[0].Timestamp
+	var sumX, sumY, sumXY, sumXX float64
+	for _, sn := range s.snapshots {
+		x := sn.Timestamp.Sub(start).Seconds()
+		y := float64(sn.Memory.HeapAlloc)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	n := float64(len(s.snapshots))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	return (n*sumXY - sumX*sumY) / denominator
+}
+
+// GoroutineTrend returns the number of goroutines in each snapshot, oldest first
+func (s *Series) GoroutineTrend() []int {
+	trend := make([]int, len(s.snapshots))
+	for i, sn := range s.snapshots {
+		trend[i] = sn.NumGoRoutines
+	}
+	return trend
+}
+
+// IsLeaking returns true if the series shows steady growth of either the heap or the number of goroutines, which
+// suggests a memory or goroutine leak.
+//
+// The heap grows and shrinks with every garbage collection, so the series is divided into three windows of consecutive
+// snapshots and only the lowest value in each window is compared, which approximates the memory that is still in use
+// after collection. A leak is only reported if the lowest value grows from each window to the next, and by at least 10%
+// from the first window to the last. The series must have at least six snapshots, otherwise false is returned. The heap
+// is only considered if a garbage collection ran during every window. Collect snapshots over a longer period to avoid
+// mistaking a slowly filling cache or a warm up for a leak.
+func (s *Series) IsLeaking() bool {
+	if len(s.snapshots) < seriesWindows*2 {
+		return false
+	}
+
+	heap := make([]float64, len(s.snapshots))
+	goroutines := make([]float64, len(s.snapshots))
+	for i, sn := range s.snapshots {
+		heap[i] = float64(sn.Memory.HeapAlloc)
+		goroutines[i] = float64(sn.NumGoRoutines)
+	}
+	return (collectedEachWindow(s.snapshots) && isGrowing(heap)) || isGrowing(goroutines)
+}
+
+// collectedEachWindow returns true if a garbage collection ran during each window of snapshots
+func collectedEachWindow(snapshots []Snapshot) bool {
+	for w := 0; w < seriesWindows; w++ {
+		window := snapshots[w*len(snapshots)/seriesWindows : (w+1)*len(snapshots)/seriesWindows]
+		if window[len(window)-1].Memory.NumGC <= window[0].Memory.NumGC {
+			return false
+		}
+	}
+	return true
+}
+
+// isGrowing returns true if the lowest value of each window of values is greater than the one before it, and the last
+// is at least seriesMinGrowth greater than the first
+func isGrowing(values []float64) bool {
+	minimums := make([]float64, seriesWindows)
+	for w := 0; w < seriesWindows; w++ {
+		window := values[w*len(values)/seriesWindows : (w+1)*len(values)/seriesWindows]
+		minimums[w] = window[0]
+		for _, value := range window {
+			if value < minimums[w] {
+				minimums[w] = value
+			}
+		}
+		if w > 0 && minimums[w] <= minimums[w-1] {
+			return false
+		}
+	}
+	return minimums[seriesWindows-1] >= minimums[0]*(1+seriesMinGrowth)
+}
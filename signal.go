@@ -0,0 +1,51 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+)
+
+// captureLock serializes snapshots that are triggered in the background
+var captureLock sync.Mutex
+
+// OnSignal will install a handler that writes a full snapshot, like Full, to a new file in dir each time the process
+// receives sig. Call the returned stop function to uninstall the handler, it is safe to call more than once.
+//
+// Snapshots are taken one at a time, signals received while a snapshot is being written are coalesced into a single
+// following snapshot. Errors writing a snapshot are printed to stderr.
+//
+// Example:
+//
+//	stop := snapshot.OnSignal(syscall.SIGUSR1, "/var/tmp")
+//	defer stop()
+func OnSignal(sig os.Signal, dir string) (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, sig)
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-c:
+				captureLock.Lock()
+				if err := Full(filepath.Join(dir, DefaultFileName())); err != nil {
+					fmt.Fprintf(os.Stderr, "snapshot: error writing snapshot on signal %s: %s\n", sig, err.Error())
+				}
+				captureLock.Unlock()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(c)
+			close(done)
+		})
+	}
+}
@@ -3,109 +3,608 @@ package snapshot
 
 import (
 	"archive/zip"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
-	"runtime/pprof"
+	"time"
 )
 
+// Version is the version of this package. It is included in snapshot.json and manifest.json so that tools reading a
+// snapshot can tell which version of the format it is in.
+const Version = "1.1.0"
+
 // Snapshot describes a snapshot of a running go program.
+//
+// The names of fields in JSON are snake_case and are kept stable, new fields may be added over time.
 type Snapshot struct {
-	Memory        runtime.MemStats
-	GC            debug.GCStats
-	Stack         string
-	BuildInfo     debug.BuildInfo
-	NumGoRoutines int
-	Pid           int
-	Uid           int
-	Gid           int
-	Environ       []string
-	Executable    string
-	Wd            string
-	Hostname      string
+	// SnapshotVersion is the version of this package that collected the snapshot, see Version
+	SnapshotVersion string `json:"snapshot_version"`
+	// Timestamp is when the snapshot was collected. It is encoded in RFC 3339 format in JSON.
+	Timestamp time.Time `json:"timestamp"`
+	Pid       int       `json:"pid"`
+	// PPid is the ID of the parent process
+	PPid int `json:"ppid"`
+	// StartTime is when the process started. On Linux it is only accurate to about a second. On platforms other than
+	// Linux and Windows, this is when this package was initialized, which is shortly after the process started.
+	StartTime time.Time `json:"start_time"`
+	// Uptime is how long the process had been running when the snapshot was collected. It is encoded as a duration
+	// string in JSON, such as "72h3m0.5s".
+	Uptime time.Duration `json:"-"`
+	// Uid is the user ID of the process, or -1 if unknown, in which case it is left out of JSON. It is always -1 on
+	// Windows, see Username.
+	Uid int `json:"uid"`
+	// Gid is the group ID of the process, or -1 if unknown, in which case it is left out of JSON. It is always -1 on
+	// Windows, see Username.
+	Gid int `json:"gid"`
+	// Username is the name of the user running the process, such as "DOMAIN\user". This is only populated on Windows,
+	// which has no user or group IDs.
+	Username   string `json:"username,omitempty"`
+	Hostname   string `json:"hostname,omitempty"`
+	Executable string `json:"executable,omitempty"`
+	// ExecutableReal is Executable with all symbolic links resolved. If it differs from Executable, the application was
+	// started through a symbolic link, such as one that is switched between releases when deploying.
+	ExecutableReal string `json:"executable_real,omitempty"`
+	Wd             string `json:"wd,omitempty"`
+	// WdReal is Wd with all symbolic links resolved
+	WdReal  string   `json:"wd_real,omitempty"`
+	Environ []string `json:"environ,omitempty"`
+	// RuntimeEnv are the environment variables that change the behavior of the go runtime, such as GOGC and GODEBUG.
+	// Like Environ, it is empty if the environment was skipped.
+	RuntimeEnv RuntimeEnv `json:"runtime_env"`
+	// Extra is any additional information provided by the application, see CollectWith.
+	Extra     map[string]any  `json:"extra,omitempty"`
+	BuildInfo debug.BuildInfo `json:"build_info"`
+	// GoVersion is the version of go the application was built with
+	GoVersion string `json:"go_version"`
+	// VCSRevision is the version control revision the application was built from, if known
+	VCSRevision string `json:"vcs_revision,omitempty"`
+	// VCSTime is the time of VCSRevision in RFC 3339 format, if known
+	VCSTime string `json:"vcs_time,omitempty"`
+	// VCSModified is true if the application was built from a working tree with uncommitted changes
+	VCSModified bool `json:"vcs_modified,omitempty"`
+	// CgoEnabled is true if the application was built with cgo enabled. Without cgo, NumCgoCall stays at zero and
+	// native libraries can't be loaded.
+	CgoEnabled bool `json:"cgo_enabled"`
+	// Git describes the git repository containing Wd, only populated if CollectOptions.IncludeGit is set and Wd is
+	// within a repository
+	Git *GitInfo `json:"git,omitempty"`
+	// CPU describes the CPUs available to the process and the load of the host
+	CPU CPUInfo `json:"cpu"`
+	// Container describes the container the process is running in, if any, and its resource limits
+	Container ContainerInfo `json:"container"`
+	// Pressure is how much time tasks spent stalled waiting for memory, CPU and IO. This is only populated on Linux.
+	Pressure PressureInfo `json:"pressure"`
+	// Disk is the usage of the filesystem containing the working directory. Only the path is populated on platforms
+	// where DiskUsage is not supported.
+	Disk          DiskStats `json:"disk"`
+	NumGoRoutines int       `json:"num_goroutines"`
+	// NumThreads is the number of OS threads used by the runtime. A number of threads much higher than GOMAXPROCS
+	// usually means goroutines are blocked in system calls or cgo calls.
+	NumThreads int `json:"num_threads"`
+	// NumCgoCall is the number of cgo calls made by the process since it started. This is a cumulative count, not the
+	// number of calls currently in progress. Compare it between snapshots to find out how often cgo is called, a thread
+	// blocked in a cgo call shows up as a high NumThreads.
+	NumCgoCall int64 `json:"num_cgo_call"`
+	// Goroutines describes every goroutine in the process. Use GoroutineStates for the number of goroutines in each
+	// state.
+	Goroutines []GoroutineInfo `json:"goroutines"`
+	// GoroutineLabels is the number of goroutines with each pprof label, keyed by "key=value". Labels are set with
+	// pprof.Do or pprof.SetGoroutineLabels, and are inherited by goroutines started while they are set.
+	GoroutineLabels map[string]int `json:"goroutine_labels,omitempty"`
+	// Stack is the stack of only the goroutine that collected the snapshot, which shows what led to the snapshot being
+	// taken. The stacks of all goroutines are in Goroutines, and in stack.txt in a full snapshot.
+	Stack  string           `json:"stack"`
+	Memory runtime.MemStats `json:"memory"`
+	// GC are statistics about garbage collection. PauseQuantiles contains the minimum, 25th percentile, median, 75th
+	// percentile, and maximum pause durations.
+	GC debug.GCStats `json:"gc"`
+	// MemoryLimit is the soft memory limit of the go runtime in bytes, set by GOMEMLIMIT or debug.SetMemoryLimit. It is
+	// math.MaxInt64 if there is no limit. The garbage collector runs more often as the limit is approached.
+	MemoryLimit int64 `json:"memory_limit"`
+	// GCPercent is the GOGC value, set by GOGC or debug.SetGCPercent, or -1 if the garbage collector is disabled
+	GCPercent int `json:"gc_percent"`
+	// MemoryPressure is the memory used by the go runtime, Memory.Sys minus Memory.HeapReleased, as a fraction of the
+	// lower of MemoryLimit and the memory limit of the container. It is 0 if there is neither limit. Values approaching
+	// or above 1 mean the garbage collector is struggling to stay within the limit, and a process in a container is
+	// about to run out of memory. See IsUnderMemoryPressure.
+	MemoryPressure float64 `json:"memory_pressure"`
+	// MemProfileRate is runtime.MemProfileRate, the average number of bytes allocated between each allocation recorded
+	// in the heap profile. A heap profile with few samples in a function does not mean it allocates little, unless the
+	// rate is 1, which records every allocation. Zero means allocations are not recorded.
+	MemProfileRate int `json:"mem_profile_rate"`
+	// Metrics are all metrics reported by the runtime/metrics package, keyed by name. Values are either a uint64, a
+	// float64, or a Histogram. This is more detailed than Memory and GC, and includes metrics such as scheduler
+	// latency. When loaded from JSON, numbers are float64 and histograms are map[string]any.
+	Metrics map[string]any `json:"metrics"`
+	// NumFD is the number of file descriptors open in the process. This is only populated on Linux.
+	NumFD int `json:"num_fd"`
+	// FDLimit is the limit on the number of file descriptors the process can have open. This is only populated on
+	// Unix platforms. Compare it with NumFD to find out how close the process is to running out.
+	FDLimit FDLimit `json:"fd_limit"`
+	// MappedLibraries are the paths of the shared libraries loaded into the process, in the order they were loaded. Unlike
+	// BuildInfo, which only lists go modules, this shows the native libraries a cgo application is actually using. This
+	// is only populated on Linux, and is empty on all other platforms.
+	MappedLibraries []string `json:"mapped_libraries,omitempty"`
+	// OpenFiles are the file descriptors open in the process. This is only populated on Linux, and is empty on all
+	// other platforms.
+	OpenFiles []OpenFile `json:"open_files,omitempty"`
+	// Connections are the TCP and UDP sockets open in the process. This is only populated on Linux, and is empty on
+	// all other platforms.
+	Connections []Connection `json:"connections,omitempty"`
+	// Listeners are the addresses of the listeners registered with RegisterListener, keyed by the name they were
+	// registered with
+	Listeners map[string]string `json:"listeners,omitempty"`
+	// Rates are how quickly memory was allocated and collected, only populated by CollectRate
+	Rates Rates `json:"rates"`
+	// PanicValue is the value of the panic that the snapshot was taken for, formatted with fmt. It is only populated by
+	// CollectWithPanic and CaptureOnPanic.
+	PanicValue string `json:"panic_value,omitempty"`
+	// PanicStack is the stack of the panicking goroutine when the panic was recovered, which shows where it happened
+	PanicStack string `json:"panic_stack,omitempty"`
+	// Notes describe anything unusual about how a full snapshot was taken, such as artifacts that were skipped
+	Notes []string `json:"notes,omitempty"`
 }
 
 // Collect will take a snapshot of useful statistics of your running Go application. This should not have a major impact
-// on your running application.
+// on your running application, execution is only paused very briefly to read memory statistics and the stacks of all
+// goroutines. The pause to read the stacks grows with the number of goroutines. Collect also reads a number of files
+// from /proc on Linux. Use CollectLight for frequent collection on a hot path.
+//
+// Any information that could not be collected is left empty. Use CollectE to find out why.
 func Collect() (s Snapshot) {
+	s, _ = CollectE()
+	return
+}
+
+// CollectE will take a snapshot of useful statistics of your running Go application, like Collect, and return the first
+// error encountered while doing so. Collection does not stop at the first error, the returned snapshot always contains
+// all information that could be collected even if an error is returned. The error is a *PartialError.
+func CollectE() (Snapshot, error) {
+	s, err := collect(false, CollectOptions{})
+	if err != nil {
+		return s, &PartialError{Snapshot: &s, Err: err}
+	}
+	return s, nil
+}
+
+// CollectLight will take a snapshot of only the information that is cheap to collect, without pausing execution of
+// your application at all. It is much cheaper than Collect, making it suitable for frequent monitoring.
+//
+// Only the following are populated: SnapshotVersion, Timestamp, GoVersion, Pid, PPid, StartTime, Uptime, Uid, Gid,
+// Username, Hostname, Executable, ExecutableReal, Wd, WdReal, Environ, RuntimeEnv, Listeners, CPU (except load
+// averages), NumGoRoutines, NumThreads, NumCgoCall, NumFD, and FDLimit. Memory and GC statistics, goroutine details, and stacks
+// are left out as reading them is what pauses execution in Collect.
+func CollectLight() Snapshot {
+	s, _ := collect(true, CollectOptions{})
+	return s
+}
+
+func collect(light bool, opts CollectOptions) (s Snapshot, err error) {
+	setErr := func(e error) {
+		if err == nil {
+			err = e
+		}
+	}
+
+	s.SnapshotVersion = Version
+	s.Timestamp = opts.now()
+	s.GoVersion = runtime.Version()
+	s.NumGoRoutines = runtime.NumGoroutine()
+	s.NumThreads = numThreads()
+	s.NumCgoCall = runtime.NumCgoCall()
+	s.CPU.NumCPU = runtime.NumCPU()
+	s.CPU.GOMAXPROCS = runtime.GOMAXPROCS(0)
+	s.Pid = os.Getpid()
+	s.PPid = os.Getppid()
+	if start, e := processStartTime(); e == nil {
+		s.StartTime = start
+		s.Uptime = time.Since(start)
+	} else {
+		setErr(fmt.Errorf("start time: %s", e.Error()))
+	}
+	readIdentity(&s)
+	if !opts.SkipEnviron {
+		s.Environ = redactEnviron(os.Environ(), opts.RedactEnviron)
+		s.RuntimeEnv = readRuntimeEnv()
+	}
+	s.Extra = opts.Extra
+	if exe, e := os.Executable(); e == nil {
+		s.Executable = exe
+		if real, e := filepath.EvalSymlinks(exe); e == nil {
+			s.ExecutableReal = real
+		} else {
+			setErr(fmt.Errorf("executable: %s", e.Error()))
+		}
+	} else {
+		setErr(fmt.Errorf("executable: %s", e.Error()))
+	}
+	if wd, e := os.Getwd(); e == nil {
+		s.Wd = wd
+		if real, e := filepath.EvalSymlinks(wd); e == nil {
+			s.WdReal = real
+		} else {
+			setErr(fmt.Errorf("wd: %s", e.Error()))
+		}
+	} else {
+		setErr(fmt.Errorf("wd: %s", e.Error()))
+	}
+	if hostname, e := os.Hostname(); e == nil {
+		s.Hostname = hostname
+	} else {
+		setErr(fmt.Errorf("hostname: %s", e.Error()))
+	}
+
+	s.Listeners = registeredListenerAddrs()
+
+	if n, e := numFDs(); e == nil {
+		s.NumFD = n
+	} else {
+		setErr(fmt.Errorf("fds: %s", e.Error()))
+	}
+	if limit, e := fdLimit(); e == nil {
+		s.FDLimit = limit
+	} else {
+		setErr(fmt.Errorf("fd limit: %s", e.Error()))
+	}
+
+	if light {
+		return
+	}
+
 	runtime.ReadMemStats(&s.Memory)
+	s.GC.PauseQuantiles = make([]time.Duration, 5)
 	debug.ReadGCStats(&s.GC)
-	buildInfo, _ := debug.ReadBuildInfo()
-	s.BuildInfo = *buildInfo
+	readGCSettings(&s)
+	s.MemProfileRate = runtime.MemProfileRate
+	s.Metrics = readMetrics()
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		s.BuildInfo = *buildInfo
+		readBuildSettings(&s, buildInfo)
+	} else {
+		setErr(fmt.Errorf("build info: not available"))
+	}
 	s.Stack = string(debug.Stack())
-	s.NumGoRoutines = runtime.NumGoroutine()
-	s.Pid = os.Getpid()
-	s.Uid = os.Getuid()
-	s.Gid = os.Getgid()
-	s.Environ = os.Environ()
-	exe, _ := os.Executable()
-	s.Executable = exe
-	wd, _ := os.Getwd()
-	s.Wd = wd
-	hostname, _ := os.Hostname()
-	s.Hostname = hostname
+	s.Goroutines = parseGoroutines(allStacks())
+	if labels, e := readGoroutineLabels(); e == nil {
+		s.GoroutineLabels = labels
+	} else {
+		setErr(fmt.Errorf("goroutine labels: %s", e.Error()))
+	}
+	s.Container = containerInfo()
+	s.Pressure = readPressure(s.Container.Detected)
+	if libraries, e := mappedLibraries(); e == nil {
+		s.MappedLibraries = libraries
+	} else {
+		setErr(fmt.Errorf("mapped libraries: %s", e.Error()))
+	}
+	s.MemoryPressure = memoryPressure(s)
+	if e := loadAverage(&s.CPU); e != nil {
+		setErr(fmt.Errorf("load average: %s", e.Error()))
+	}
+	if s.Wd != "" {
+		if disk, e := diskUsage(s.Wd); e == nil {
+			s.Disk = disk
+		} else {
+			setErr(fmt.Errorf("disk: %s", e.Error()))
+		}
+	}
+	if opts.IncludeGit && s.Wd != "" {
+		s.Git = readGit(s.Wd)
+	}
+	if files, e := openFiles(); e == nil {
+		s.OpenFiles = files
+		if conns, e := connections(files); e == nil {
+			s.Connections = conns
+		} else {
+			setErr(fmt.Errorf("connections: %s", e.Error()))
+		}
+	} else {
+		setErr(fmt.Errorf("open files: %s", e.Error()))
+	}
 
 	return
 }
 
+// CollectWith will take a snapshot, like Collect, that includes extra information provided by your application, such as
+// its version or active feature flags. The values of extra must be able to be encoded as JSON.
+func CollectWith(extra map[string]any) Snapshot {
+	s := Collect()
+	s.Extra = extra
+	return s
+}
+
+// CollectWithOptions will take a snapshot, like Collect, with the environment and extra information controlled by opts.
+func CollectWithOptions(opts CollectOptions) Snapshot {
+	s, _ := collect(false, opts)
+	return s
+}
+
+// CollectJSON will take a snapshot, like Collect, and write it to w as JSON in the same format as snapshot.json.
+func CollectJSON(w io.Writer) error {
+	return Collect().WriteJSON(w)
+}
+
+// MarshalJSON encodes the snapshot as JSON, with Uptime as a duration string. Uid and Gid are left out if they are
+// unknown.
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	type snapshot Snapshot
+	encoded := struct {
+		snapshot
+		Uptime string `json:"uptime"`
+		Uid    *int   `json:"uid,omitempty"`
+		Gid    *int   `json:"gid,omitempty"`
+	}{snapshot: snapshot(s), Uptime: s.Uptime.String()}
+	if s.Uid != -1 {
+		encoded.Uid = &s.Uid
+	}
+	if s.Gid != -1 {
+		encoded.Gid = &s.Gid
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes a snapshot from JSON, see MarshalJSON. It is tolerant of snapshots written by other versions of
+// this package, see Load.
+func (s *Snapshot) UnmarshalJSON(data []byte) error {
+	return decodeSnapshot(data, s, false)
+}
+
+// decodeSnapshot decodes a snapshot from JSON. If strict is false, unknown fields are ignored and fields whose type
+// doesn't match are left zero. If strict is true, both are an error.
+func decodeSnapshot(data []byte, s *Snapshot, strict bool) error {
+	type snapshot Snapshot
+	decoded := struct {
+		*snapshot
+		Uptime string `json:"uptime"`
+		Uid    *int   `json:"uid"`
+		Gid    *int   `json:"gid"`
+	}{snapshot: (*snapshot)(s)}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&decoded); err != nil {
+		// The rest of the snapshot is still decoded when the type of a field doesn't match
+		var typeErr *json.UnmarshalTypeError
+		if strict || !errors.As(err, &typeErr) {
+			return err
+		}
+	}
+
+	// Uid and Gid are left out when they are unknown, and must not be mistaken for root
+	s.Uid, s.Gid = -1, -1
+	if decoded.Uid != nil {
+		s.Uid = *decoded.Uid
+	}
+	if decoded.Gid != nil {
+		s.Gid = *decoded.Gid
+	}
+
+	s.Uptime = 0
+	if decoded.Uptime == "" {
+		return nil
+	}
+	uptime, err := time.ParseDuration(decoded.Uptime)
+	if err != nil && strict {
+		return fmt.Errorf("uptime: %s", err.Error())
+	}
+	s.Uptime = uptime
+	return nil
+}
+
+// WriteJSON will write the snapshot to w as JSON indented with four spaces, in the same format as snapshot.json.
+func (s Snapshot) WriteJSON(w io.Writer) error {
+	return s.WriteJSONIndent(w, "    ")
+}
+
+// WriteJSONIndent will write the snapshot to w as JSON, with each level of nesting indented by indent. If indent is
+// empty, the JSON is compact and written on a single line.
+func (s Snapshot) WriteJSONIndent(w io.Writer, indent string) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", indent)
+	return encoder.Encode(s)
+}
+
 // Full will take a full detailed snapshot of your go application, including memory dumps, and save it as a ZIP file at
-// the given path. fileName should end with ".zip"
+// the given path. fileName must end with ".zip", otherwise ErrInvalidExtension is returned.
 //
 // The ZIP file will contain the following items:
 //   - snapshot.json: Statistics about the running application and environment
 //   - heap.bin: A heap dump. The format is described in https://github.com/golang/go/wiki/heapdump15-through-heapdump17
+//   - heap.pprof: A heap profile, which can be opened with `go tool pprof` and compared with DiffHeapProfiles
+//   - summary.json: The most important numbers of snapshot.json, in a stable layout, see Summary
 //   - stack.txt: A text file with the stacks of all goroutines
+//   - caller-stack.txt: A text file with the stack of the goroutine that took the snapshot
+//   - sched.txt: A summary of the state of the scheduler
+//   - manifest.json: The size and SHA-256 hash of every other file, see Manifest
 //
-// Warning: this will temporarily suspend all execution of your application. The size of the output file will be at most
-// the amount of memory used by the go application.
+// Warning: this will temporarily suspend all execution of your application while the heap dump is written. The size of
+// the output file will be at most the amount of memory used by the go application. Use FullWithOptions to leave out the
+// heap dump.
 func Full(fileName string) error {
-	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
-	if err != nil {
-		return fmt.Errorf("open: %s", err.Error())
-	}
-	defer f.Close()
+	return FullWithOptions(fileName, DefaultOptions())
+}
 
-	zw := zip.NewWriter(f)
-	sn := Collect()
+// FullWithOptions will take a snapshot of your go application containing only the artifacts selected by opts, and save
+// it as a ZIP file at the given path. fileName must end with ".zip".
+//
+// The archive is written to a temporary file in the same directory and only renamed to fileName once it is complete.
+// If an error is returned, an existing file at fileName is left as it was.
+//
+// Only the heap dump (Options.IncludeHeapDump) will suspend all execution of your application for a significant amount
+// of time. Without it, execution is only paused for as long as it takes to read memory statistics and goroutine stacks,
+// see Options for details.
+func FullWithOptions(fileName string, opts Options) error {
+	return FullContextWithOptions(context.Background(), fileName, opts)
+}
 
-	snapshotFile, err := zw.Create("snapshot.json")
-	if err != nil {
-		return fmt.Errorf("snapshot: %s", err.Error())
-	}
+// FullContext will take a full detailed snapshot of your go application, like Full, stopping early if ctx is cancelled.
+// If ctx is cancelled, ctx.Err() is returned and the partially written file is removed.
+//
+// The context is checked between writing each artifact and while copying the heap dump into the archive. Writing the
+// heap dump itself cannot be interrupted.
+func FullContext(ctx context.Context, fileName string) error {
+	return FullContextWithOptions(ctx, fileName, DefaultOptions())
+}
 
-	encoder := json.NewEncoder(snapshotFile)
-	encoder.SetIndent("", "    ")
-	if err := encoder.Encode(sn); err != nil {
-		return fmt.Errorf("snapshot: %s", err.Error())
+// FullContextWithOptions will take a snapshot of your go application containing only the artifacts selected by opts,
+// like FullWithOptions, stopping early if ctx is cancelled. See FullContext.
+func FullContextWithOptions(ctx context.Context, fileName string, opts Options) error {
+	if err := checkFileName(fileName, ".zip"); err != nil {
+		return err
 	}
+	opts = opts.withTempDir(filepath.Dir(fileName))
+	return withSeparateHeapDump(fileName, opts, func(opts Options) error {
+		return writeFileAtomic(fileName, func(w io.Writer) error {
+			return FullToContextWithOptions(ctx, w, opts)
+		})
+	})
+}
 
-	traceFile, err := zw.Create("stack.txt")
+// writeFileAtomic calls write with a temporary file in the same directory as fileName, and renames it to fileName once
+// write succeeds. If anything fails the temporary file is removed, and any existing file at fileName is left as it was.
+func writeFileAtomic(fileName string, write func(w io.Writer) error) error {
+	f, err := createTempFile(fileName)
 	if err != nil {
-		return fmt.Errorf("trace: %s", err.Error())
+		return stageErr(ErrOpen, err)
+	}
+	tmpName := f.Name()
+
+	if err := write(f); err != nil {
+		f.Close()
+		os.Remove(tmpName)
+		return err
 	}
-	if err := pprof.Lookup("goroutine").WriteTo(traceFile, 1); err != nil {
-		return fmt.Errorf("trace: %s", err.Error())
+	if err := f.Close(); err != nil {
+		os.Remove(tmpName)
+		return stageErr(ErrClose, err)
 	}
+	if err := os.Rename(tmpName, fileName); err != nil {
+		os.Remove(tmpName)
+		return stageErr(ErrClose, err)
+	}
+	return nil
+}
 
-	tmpFile, err := os.CreateTemp("", "dump")
-	if err != nil {
-		return fmt.Errorf("dump: %s", err.Error())
+// createTempFile creates a new temporary file in the same directory as fileName, which can be renamed to it once it is
+// complete
+func createTempFile(fileName string) (*os.File, error) {
+	tmpName := fmt.Sprintf("%s.%d.tmp", fileName, time.Now().UnixNano())
+	return os.OpenFile(tmpName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, os.ModePerm)
+}
+
+// FullWithCPUProfile will take a full detailed snapshot of your go application, like Full, and include a CPU profile
+// sampled for the given duration as cpu.pprof. This will block for at least d.
+//
+// Warning: this will temporarily suspend all execution of your application while the heap dump is written.
+func FullWithCPUProfile(fileName string, d time.Duration) error {
+	opts := DefaultOptions()
+	opts.CPUProfileDuration = d
+	return FullWithOptions(fileName, opts)
+}
+
+// FullWithTrace will take a full detailed snapshot of your go application, like Full, and include an execution trace
+// recorded for the given duration as trace.out, which can be opened with `go tool trace`. This will block for at least
+// d. See Options.TraceDuration for the overhead of tracing.
+//
+// Warning: this will temporarily suspend all execution of your application while the heap dump is written.
+func FullWithTrace(fileName string, d time.Duration) error {
+	opts := DefaultOptions()
+	opts.TraceDuration = d
+	return FullWithOptions(fileName, opts)
+}
+
+// FullTo will take a full detailed snapshot of your go application, like Full, and write the ZIP file to w. This can be
+// used to stream a snapshot to a network connection or into memory.
+//
+// The heap dump must be written to a file, so a temporary file is still created and removed when the snapshot is
+// finished, see Options.TempDir.
+//
+// Warning: this will temporarily suspend all execution of your application while the heap dump is written. Use
+// FullToWithOptions to leave out the heap dump.
+func FullTo(w io.Writer) error {
+	return FullToWithOptions(w, DefaultOptions())
+}
+
+// FullBytes will take a full detailed snapshot of your go application, like Full, and return the ZIP file in memory. The
+// heap dump is still written to a temporary file first, which is removed once the snapshot is finished.
+//
+// Warning: the returned slice can be as large as the heap dump plus all other artifacts, which is up to the amount of
+// memory used by the go application. Taking a snapshot this way can therefore roughly double the memory used by your
+// application. This will also temporarily suspend all execution of your application while the heap dump is written.
+func FullBytes() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := FullTo(buf); err != nil {
+		return nil, err
 	}
-	debug.WriteHeapDump(tmpFile.Fd())
-	tmpFile.Seek(0, 0)
+	return buf.Bytes(), nil
+}
 
-	dumpFile, err := zw.Create("heap.bin")
-	if err != nil {
-		return fmt.Errorf("dump: %s", err.Error())
+// FullToWithOptions will take a snapshot of your go application containing only the artifacts selected by opts, and
+// write the ZIP file to w.
+//
+// Only the heap dump (Options.IncludeHeapDump) will suspend all execution of your application for a significant amount
+// of time, see FullWithOptions.
+func FullToWithOptions(w io.Writer, opts Options) error {
+	return FullToContextWithOptions(context.Background(), w, opts)
+}
+
+// FullToContextWithOptions will take a snapshot of your go application containing only the artifacts selected by opts,
+// and write the ZIP file to w, stopping early if ctx is cancelled. If ctx is cancelled, ctx.Err() is returned and the
+// data written to w will not be a complete ZIP file. See FullContext.
+func FullToContextWithOptions(ctx context.Context, w io.Writer, opts Options) error {
+	if err := opts.validate(); err != nil {
+		return err
 	}
 
-	io.Copy(dumpFile, tmpFile)
-	tmpFile.Close()
-	os.Remove(tmpFile.Name())
+	zw := newZipWriter(w, opts)
+	if err := writeArchive(ctx, &zipArchive{zw: zw, opts: opts}, opts); err != nil {
+		return err
+	}
 
-	zw.Close()
+	if err := zw.Close(); err != nil {
+		return stageErr(ErrZip, err)
+	}
 	return nil
 }
+
+// WriteTo will take a full detailed snapshot of your go application, like Full, and add its artifacts to zw in the
+// directory prefix, such as "snapshot/". If prefix is empty, artifacts are placed at the root of the archive. zw is not
+// closed, so that you can add your own files to the same archive, such as logs or configuration.
+//
+// Files are compressed using the compressor registered with zw, Options.CompressionLevel is not used. Files given a level
+// of flate.NoCompression by Options.FileCompressionLevels are still stored without compression.
+//
+// Warning: this will temporarily suspend all execution of your application while the heap dump is written.
+func WriteTo(zw *zip.Writer, prefix string) error {
+	opts := DefaultOptions()
+	opts.Prefix = prefix
+	return WriteToWithOptions(zw, opts)
+}
+
+// WriteToWithOptions will take a snapshot of your go application containing only the artifacts selected by opts, and
+// add them to zw in the directory opts.Prefix, like WriteTo. zw is not closed.
+func WriteToWithOptions(zw *zip.Writer, opts Options) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	return writeArchive(context.Background(), &zipArchive{zw: &zipWriter{Writer: zw}, opts: opts}, opts)
+}
+
+// DefaultFileName returns a file name for a snapshot ZIP file that includes the hostname, the process ID, and the
+// current time, such as "snapshot-myhost-1234-20060102T150405.000Z.zip". This is the name used by OnSignal and Handler.
+//
+// The time is in UTC with millisecond precision and without colons, which are not permitted in file names on Windows,
+// so that snapshots written to the same directory by different processes or in quick succession don't collide.
+func DefaultFileName() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("snapshot-%s-%d-%s.zip", hostname, os.Getpid(), time.Now().UTC().Format("20060102T150405.000Z"))
+}
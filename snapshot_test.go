@@ -0,0 +1,44 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotJSONIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		uid      int
+		gid      int
+		username string
+		omitted  bool
+	}{
+		{"unix", 1000, 100, "", false},
+		{"root", 0, 0, "", false},
+		{"windows", -1, -1, `DOMAIN\user`, true},
+		{"unknown", -1, -1, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := json.Marshal(Snapshot{Uid: test.uid, Gid: test.gid, Username: test.username})
+			if err != nil {
+				t.Fatalf("Error encoding snapshot: %s", err.Error())
+			}
+			omitted := !strings.Contains(string(data), `"uid":`) && !strings.Contains(string(data), `"gid":`)
+			if omitted != test.omitted {
+				t.Errorf("Expected uid and gid to be omitted %t, got %s", test.omitted, data)
+			}
+
+			for _, strict := range []bool{false, true} {
+				decoded := Snapshot{}
+				if err := decodeSnapshot(data, &decoded, strict); err != nil {
+					t.Fatalf("Error decoding snapshot: %s", err.Error())
+				}
+				if decoded.Uid != test.uid || decoded.Gid != test.gid || decoded.Username != test.username {
+					t.Errorf("Expected %d/%d %q, got %d/%d %q", test.uid, test.gid, test.username, decoded.Uid, decoded.Gid, decoded.Username)
+				}
+			}
+		})
+	}
+}
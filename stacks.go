@@ -0,0 +1,201 @@
+package snapshot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stackClusterSize is the number of goroutines blocked at the same call site that is considered suspicious
+const stackClusterSize = 10
+
+// StackAnalysis is a summary of a goroutine dump, see AnalyzeStacks.
+type StackAnalysis struct {
+	// Goroutines is the number of goroutines in the dump
+	Goroutines int
+	// States is the number of goroutines in each state, such as "running", "chan receive", or "sync.Mutex.Lock"
+	States map[string]int
+	// Sites are the call sites that goroutines are blocked at, sorted by the most goroutines first
+	Sites []BlockingSite
+	// Warnings describe suspicious patterns that may indicate a deadlock or leak
+	Warnings []string
+}
+
+// BlockingSite describes goroutines that are blocked in the same state at the same call site. The call site is the
+// innermost frame outside of the runtime and sync packages, so that goroutines waiting on the same lock or channel
+// operation in your code are grouped together.
+type BlockingSite struct {
+	// Function is the name of the function at the call site
+	Function string
+	// Location is the file and line of the call site
+	Location string
+	// State is what the goroutines are waiting on, such as "chan receive"
+	State string
+	// Count is the number of goroutines blocked at the call site
+	Count int
+	// MaxWait is the longest any of the goroutines has been blocked, which is only reported in whole minutes
+	MaxWait time.Duration
+}
+
+// AnalyzeStacks will parse a goroutine dump, such as one written by Stacks, the stack.txt file in a full snapshot, or
+// the output of an unrecovered panic, and summarize where goroutines are blocked.
+//
+// The default stack.txt, written at Options.StackDebugLevel 1, groups goroutines with identical stacks and does not
+// record what each goroutine is waiting on. For it, the number of goroutines and call sites are reported with a state
+// of "unknown", and only warnings for large clusters are given. Use Stacks, or stack.txt at StackDebugLevel 2, for
+// states, wait durations, and every warning.
+//
+// Warnings are given for large clusters of goroutines blocked at the same call site, goroutines that have been waiting
+// on a mutex for at least a minute, and goroutines blocked forever on a nil channel. A dump does not record which
+// goroutine will eventually send or receive on a channel, so cycles of channel waits can't be identified exactly.
+// Instead, a warning is given when every goroutine is blocked on a channel or lock, which is how a deadlock appears.
+func AnalyzeStacks(dump string) StackAnalysis {
+	stacks := parseGoroutineStacks(dump)
+	analysis := StackAnalysis{
+		Goroutines: len(stacks),
+		States:     map[string]int{},
+	}
+
+	sites := map[BlockingSite]*BlockingSite{}
+	running := 0
+	waiting := 0
+	for _, stack := range stacks {
+		analysis.States[stack.State]++
+		if isRunningState(stack.State) {
+			running++
+			continue
+		}
+		if isChannelWait(stack.State) || strings.HasPrefix(stack.State, "sync.") || stack.State == "semacquire" {
+			waiting++
+		}
+
+		function, location := callSite(stack.frames)
+		key := BlockingSite{Function: function, Location: location, State: stack.State}
+		site, ok := sites[key]
+		if !ok {
+			site = &BlockingSite{Function: function, Location: location, State: stack.State}
+			sites[key] = site
+		}
+		site.Count++
+		if stack.WaitDuration > site.MaxWait {
+			site.MaxWait = stack.WaitDuration
+		}
+	}
+
+	for _, site := range sites {
+		analysis.Sites = append(analysis.Sites, *site)
+	}
+	sort.Slice(analysis.Sites, func(i, j int) bool {
+		a, b := analysis.Sites[i], analysis.Sites[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Function != b.Function {
+			return a.Function < b.Function
+		}
+		return a.State < b.State
+	})
+
+	// The goroutine that took the dump is always running
+	if waiting > 0 && running <= 1 && waiting == len(stacks)-running {
+		analysis.Warnings = append(analysis.Warnings, "every goroutine is blocked on a channel or lock, the application is likely deadlocked")
+	}
+	for _, site := range analysis.Sites {
+		if site.Count >= stackClusterSize {
+			analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("%d goroutines blocked on %s in %s at %s", site.Count, site.State, site.Function, site.Location))
+		}
+		if strings.Contains(site.State, "(nil chan)") || site.State == "select (no cases)" {
+			analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("%d goroutines blocked forever on %s in %s at %s", site.Count, site.State, site.Function, site.Location))
+		}
+	}
+	for _, stack := range stacks {
+		if stack.WaitDuration > 0 && isLockWait(stack) {
+			function, location := callSite(stack.frames)
+			analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("goroutine %d waiting on %s for %s in %s at %s", stack.ID, stack.State, stack.WaitDuration, function, location))
+		}
+	}
+
+	return analysis
+}
+
+// String returns a readable summary of the analysis, including the ten call sites with the most blocked goroutines
+func (a StackAnalysis) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "Goroutines: %d\n", a.Goroutines)
+
+	states := make([]string, 0, len(a.States))
+	for state := range a.States {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if a.States[states[i]] != a.States[states[j]] {
+			return a.States[states[i]] > a.States[states[j]]
+		}
+		return states[i] < states[j]
+	})
+	for _, state := range states {
+		fmt.Fprintf(b, "%8d  %s\n", a.States[state], state)
+	}
+
+	if len(a.Sites) > 0 {
+		b.WriteString("\nBlocked at:\n")
+	}
+	for i, site := range a.Sites {
+		if i == 10 {
+			fmt.Fprintf(b, "  ... and %d more\n", len(a.Sites)-i)
+			break
+		}
+		wait := ""
+		if site.MaxWait > 0 {
+			wait = fmt.Sprintf(", up to %s", site.MaxWait)
+		}
+		fmt.Fprintf(b, "%8d  %s (%s%s)\n          %s\n", site.Count, site.Function, site.State, wait, site.Location)
+	}
+
+	if len(a.Warnings) > 0 {
+		b.WriteString("\nWarnings:\n")
+	}
+	for _, warning := range a.Warnings {
+		fmt.Fprintf(b, "  %s\n", warning)
+	}
+	return b.String()
+}
+
+func isRunningState(state string) bool {
+	return state == "running" || state == "runnable" || state == "syscall"
+}
+
+func isChannelWait(state string) bool {
+	return strings.HasPrefix(state, "chan receive") || strings.HasPrefix(state, "chan send") || strings.HasPrefix(state, "select")
+}
+
+// isLockWait returns true if the goroutine is waiting to acquire a sync.Mutex or sync.RWMutex. Since Go 1.20 the state
+// names the lock, older versions only report "semacquire".
+func isLockWait(stack goroutineStack) bool {
+	if strings.HasPrefix(stack.State, "sync.Mutex.") || strings.HasPrefix(stack.State, "sync.RWMutex.") {
+		return true
+	}
+	if stack.State != "semacquire" {
+		return false
+	}
+	for _, frame := range stack.frames {
+		if strings.HasPrefix(frame.function, "sync.(*Mutex).") || strings.HasPrefix(frame.function, "sync.(*RWMutex).") {
+			return true
+		}
+	}
+	return false
+}
+
+// callSite returns the innermost frame outside of the runtime and sync packages, or the innermost frame otherwise
+func callSite(frames []stackFrame) (function string, location string) {
+	if len(frames) == 0 {
+		return "", ""
+	}
+	for _, frame := range frames {
+		if !strings.HasPrefix(frame.function, "runtime.") && !strings.HasPrefix(frame.function, "sync.") && !strings.HasPrefix(frame.function, "internal/") {
+			return frame.function, frame.location
+		}
+	}
+	return frames[0].function, frames[0].location
+}
@@ -0,0 +1,113 @@
+package snapshot
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// blockedWorker blocks on ch, so that the test has a cluster of goroutines at a known call site
+func blockedWorker(ch chan struct{}, started *sync.WaitGroup) {
+	started.Done()
+	<-ch
+}
+
+// startBlockedWorkers starts n blockedWorkers, which are stopped once the test is done
+func startBlockedWorkers(t *testing.T, n int) {
+	ch := make(chan struct{})
+	started := &sync.WaitGroup{}
+	started.Add(n)
+	stopped := &sync.WaitGroup{}
+	stopped.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer stopped.Done()
+			blockedWorker(ch, started)
+		}()
+	}
+	started.Wait()
+	t.Cleanup(func() {
+		close(ch)
+		stopped.Wait()
+	})
+}
+
+func TestAnalyzeStacksDefaultStackTxt(t *testing.T) {
+	startBlockedWorkers(t, stackClusterSize+2)
+
+	opts := DefaultOptions()
+	opts.IncludeHeapDump = false
+	buf := &bytes.Buffer{}
+	if err := FullToWithOptions(buf, opts); err != nil {
+		t.Fatalf("Error taking snapshot: %s", err.Error())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Error reading snapshot: %s", err.Error())
+	}
+	var stackTxt string
+	for _, file := range zr.File {
+		if file.Name != "stack.txt" {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			t.Fatalf("Error opening stack.txt: %s", err.Error())
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("Error reading stack.txt: %s", err.Error())
+		}
+		stackTxt = string(data)
+	}
+	if !strings.HasPrefix(stackTxt, "goroutine profile: total ") {
+		t.Fatalf("stack.txt is not a debug level 1 goroutine profile:\n%s", stackTxt)
+	}
+
+	analysis := AnalyzeStacks(stackTxt)
+	if analysis.Goroutines < stackClusterSize+2 {
+		t.Errorf("Expected at least %d goroutines, got %d", stackClusterSize+2, analysis.Goroutines)
+	}
+	if analysis.States[unknownGoroutineState] != analysis.Goroutines {
+		t.Errorf("Expected every goroutine to have an unknown state, got %v", analysis.States)
+	}
+
+	found := false
+	for _, site := range analysis.Sites {
+		if strings.HasSuffix(site.Function, "snapshot.blockedWorker") {
+			found = true
+			if site.Count != stackClusterSize+2 {
+				t.Errorf("Expected %d goroutines at blockedWorker, got %d", stackClusterSize+2, site.Count)
+			}
+			if !strings.Contains(site.Location, "stacks_test.go:") {
+				t.Errorf("Unexpected location for blockedWorker: %s", site.Location)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("blockedWorker not found in sites:\n%s", analysis)
+	}
+	if len(analysis.Warnings) == 0 {
+		t.Errorf("Expected a warning for the cluster of blocked goroutines")
+	}
+}
+
+func TestAnalyzeStacksStacks(t *testing.T) {
+	startBlockedWorkers(t, stackClusterSize)
+
+	buf := &bytes.Buffer{}
+	if err := Stacks(buf); err != nil {
+		t.Fatalf("Error writing stacks: %s", err.Error())
+	}
+	analysis := AnalyzeStacks(buf.String())
+	if analysis.States["chan receive"] < stackClusterSize {
+		t.Errorf("Expected at least %d goroutines in chan receive, got %v", stackClusterSize, analysis.States)
+	}
+	if analysis.States[unknownGoroutineState] != 0 {
+		t.Errorf("Expected no goroutines with an unknown state, got %v", analysis.States)
+	}
+}
@@ -0,0 +1,20 @@
+package snapshot
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	startTimeOnce sync.Once
+	startTime     time.Time
+	startTimeErr  error
+)
+
+// processStartTime returns when the process started. It never changes, so it is only read once.
+func processStartTime() (time.Time, error) {
+	startTimeOnce.Do(func() {
+		startTime, startTimeErr = readStartTime()
+	})
+	return startTime, startTimeErr
+}
@@ -0,0 +1,76 @@
+package snapshot
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userHZ is the unit of times in /proc/self/stat, which is fixed for all programs regardless of the kernel's tick rate
+const userHZ = 100
+
+// readStartTime reads the start time of the process, which is reported in /proc/self/stat as the number of ticks since
+// the system booted
+func readStartTime() (time.Time, error) {
+	stat, err := os.ReadFile("/proc/self/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	sinceBoot, err := parseStartTime(stat)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	bootTime, err := readBootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return bootTime.Add(sinceBoot), nil
+}
+
+// parseStartTime returns how long after the system booted the process started from the contents of /proc/self/stat
+func parseStartTime(stat []byte) (time.Duration, error) {
+	// The command name is in parentheses and may contain spaces, the start time is the 20th field after it
+	end := strings.LastIndexByte(string(stat), ')')
+	if end == -1 {
+		return 0, fmt.Errorf("invalid /proc/self/stat")
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("invalid /proc/self/stat")
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	// Dividing first keeps ticks of processes on hosts that have been up for years from overflowing
+	return time.Duration(ticks) * (time.Second / userHZ), nil
+}
+
+// readBootTime reads when the system booted from /proc/stat
+func readBootTime() (time.Time, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "btime ") {
+			seconds, err := strconv.ParseInt(strings.TrimSpace(line[len("btime "):]), 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(seconds, 0), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, fmt.Errorf("no btime in /proc/stat")
+}
@@ -0,0 +1,57 @@
+package snapshot
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseStartTime(t *testing.T) {
+	stat := func(command string, ticks string) []byte {
+		return []byte(fmt.Sprintf("23817 (%s) R 23760 23817 23760 0 -1 4194304 82 0 0 0 0 0 0 0 20 0 1 0 %s 2703360 327 "+
+			"18446744073709551615 94830670934016 94830670953897 140733386239216 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0\n", command, ticks))
+	}
+
+	tests := []struct {
+		name     string
+		stat     []byte
+		expected time.Duration
+		invalid  bool
+	}{
+		{"ticks", stat("cat", "619248"), 6192480 * time.Millisecond, false},
+		{"zero", stat("init", "0"), 0, false},
+		{"command with spaces and parentheses", stat("my (app) 1 2 3", "150"), 1500 * time.Millisecond, false},
+		// About 3 years, multiplying by a second before dividing overflows
+		{"large", stat("cat", "10000000000"), 100000000 * time.Second, false},
+		{"not a number", stat("cat", "x"), 0, true},
+		{"no command", []byte("23817 R 23760"), 0, true},
+		{"too few fields", []byte("23817 (cat) R 23760 23817"), 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sinceBoot, err := parseStartTime(test.stat)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("Expected an error, got %s", sinceBoot)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error parsing start time: %s", err.Error())
+			}
+			if sinceBoot != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, sinceBoot)
+			}
+		})
+	}
+}
+
+func TestReadStartTime(t *testing.T) {
+	start, err := readStartTime()
+	if err != nil {
+		t.Fatalf("Error reading start time: %s", err.Error())
+	}
+	if start.After(time.Now()) || time.Since(start) > time.Hour {
+		t.Errorf("Unexpected start time %s", start)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !windows
+
+package snapshot
+
+import "time"
+
+// initTime is when this package was initialized, which is shortly after the process started
+var initTime = time.Now()
+
+// readStartTime returns when this package was initialized, as there is no portable way to read the start time of the
+// process on this platform
+func readStartTime() (time.Time, error) {
+	return initTime, nil
+}
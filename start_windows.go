@@ -0,0 +1,19 @@
+package snapshot
+
+import (
+	"syscall"
+	"time"
+)
+
+// readStartTime reads the creation time of the process
+func readStartTime() (time.Time, error) {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return time.Time{}, err
+	}
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(process, &creation, &exit, &kernel, &user); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, creation.Nanoseconds()), nil
+}
@@ -0,0 +1,53 @@
+package snapshot
+
+import (
+	"io"
+	"time"
+)
+
+// StreamJSON will write a snapshot to w as a single line of compact JSON, like Collect, immediately and then every
+// interval until stop is closed. This produces newline delimited JSON (NDJSON) suitable for a log aggregator.
+//
+// If w has a Flush method, such as a *bufio.Writer or an http.ResponseWriter, it is called after each snapshot.
+// Snapshots are written from the calling goroutine, StreamJSON blocks until stop is closed or a write fails, and returns
+// the error from the write. It returns nil once stop is closed and does not use w afterwards, w is not closed. If
+// interval is not positive, ErrInvalidInterval is returned without writing anything.
+func StreamJSON(w io.Writer, interval time.Duration, stop <-chan struct{}) error {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := Collect().WriteJSONIndent(w, ""); err != nil {
+			return err
+		}
+		if err := flush(w); err != nil {
+			return err
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+		// Both may be ready at once, never take another snapshot once stop is closed
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+	}
+}
+
+// flush calls the Flush method of w, if it has one
+func flush(w io.Writer) error {
+	switch f := w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case interface{ Flush() }:
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package snapshot
+
+import "time"
+
+// Summary is a compact set of the most important numbers in a snapshot, for dashboards and monitoring tools. Unlike
+// Snapshot, its layout is kept stable: fields may be added, but are never renamed or removed. It is included in a full
+// snapshot as summary.json.
+type Summary struct {
+	SnapshotVersion string    `json:"snapshot_version"`
+	Timestamp       time.Time `json:"timestamp"`
+	Hostname        string    `json:"hostname"`
+	Pid             int       `json:"pid"`
+	// UptimeSeconds is how long the process has been running, in seconds
+	UptimeSeconds float64 `json:"uptime_seconds"`
+	// HeapAlloc is the bytes of allocated heap objects, see runtime.MemStats
+	HeapAlloc uint64 `json:"heap_alloc"`
+	// HeapSys is the bytes of heap memory obtained from the OS, see runtime.MemStats
+	HeapSys uint64 `json:"heap_sys"`
+	// Sys is the total bytes of memory obtained from the OS, see runtime.MemStats
+	Sys uint64 `json:"sys"`
+	// MemoryPressure is the memory used as a fraction of the memory limit, see Snapshot.MemoryPressure
+	MemoryPressure float64 `json:"memory_pressure"`
+	NumGC          uint32  `json:"num_gc"`
+	NumGoroutines  int     `json:"num_goroutines"`
+	NumThreads     int     `json:"num_threads"`
+	NumFD          int     `json:"num_fd"`
+}
+
+// Summary returns the most important numbers in the snapshot
+func (s Snapshot) Summary() Summary {
+	return Summary{
+		SnapshotVersion: s.SnapshotVersion,
+		Timestamp:       s.Timestamp,
+		Hostname:        s.Hostname,
+		Pid:             s.Pid,
+		UptimeSeconds:   s.Uptime.Seconds(),
+		HeapAlloc:       s.Memory.HeapAlloc,
+		HeapSys:         s.Memory.HeapSys,
+		Sys:             s.Memory.Sys,
+		MemoryPressure:  s.MemoryPressure,
+		NumGC:           s.Memory.NumGC,
+		NumGoroutines:   s.NumGoRoutines,
+		NumThreads:      s.NumThreads,
+		NumFD:           s.NumFD,
+	}
+}
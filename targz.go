@@ -0,0 +1,146 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FullTarGz will take a full detailed snapshot of your go application, like Full, and save it as a gzip compressed tar
+// file at the given path instead of a ZIP file. The archive contains the same files as Full. fileName must end with
+// ".tar.gz" or ".tgz", otherwise ErrInvalidExtension is returned.
+//
+// Warning: this will temporarily suspend all execution of your application while the heap dump is written.
+func FullTarGz(fileName string) error {
+	return FullTarGzWithOptions(fileName, DefaultOptions())
+}
+
+// FullTarGzWithOptions will take a snapshot of your go application containing only the artifacts selected by opts,
+// like FullWithOptions, and save it as a gzip compressed tar file at the given path. opts.CompressionLevel is used for
+// gzip. Like FullWithOptions, an existing file at fileName is only replaced once the archive is complete.
+func FullTarGzWithOptions(fileName string, opts Options) error {
+	if err := checkFileName(fileName, ".tar.gz", ".tgz"); err != nil {
+		return err
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	opts = opts.withTempDir(filepath.Dir(fileName))
+	return withSeparateHeapDump(fileName, opts, func(opts Options) error {
+		return writeFileAtomic(fileName, func(w io.Writer) error {
+			return writeTarGz(w, opts)
+		})
+	})
+}
+
+func writeTarGz(w io.Writer, opts Options) error {
+	gz, err := gzip.NewWriterLevel(w, opts.CompressionLevel)
+	if err != nil {
+		return stageErr(ErrTar, err)
+	}
+	tw := &tarArchive{tw: tar.NewWriter(gz), tempDir: opts.TempDir}
+	defer tw.discard()
+
+	if err := writeArchive(context.Background(), tw, opts); err != nil {
+		return err
+	}
+	if err := tw.close(); err != nil {
+		return stageErr(ErrTar, err)
+	}
+	if err := gz.Close(); err != nil {
+		return stageErr(ErrTar, err)
+	}
+	return nil
+}
+
+// tarArchive is an archive that writes files into a tar file. The size of each file must be known before it is written
+// to a tar file, so files created with Create are written to a temporary file first and added to the tar file once the
+// next file is created or the archive is closed. Files whose size is already known, such as the heap dump, are created
+// with CreateSized and written directly into the tar file.
+type tarArchive struct {
+	tw      *tar.Writer
+	tempDir string
+	tmp     *os.File
+	name    string
+}
+
+func (t *tarArchive) Create(name string) (io.Writer, error) {
+	if err := t.flush(); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(t.tempDir, "snapshot")
+	if err != nil {
+		return nil, err
+	}
+	t.tmp = tmp
+	t.name = name
+	return tmp, nil
+}
+
+func (t *tarArchive) CreateSized(name string, size int64) (io.Writer, error) {
+	if err := t.flush(); err != nil {
+		return nil, err
+	}
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0644,
+		ModTime:  time.Now(),
+	}
+	if err := t.tw.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	return t.tw, nil
+}
+
+// flush adds the file currently being written, if any, to the tar file
+func (t *tarArchive) flush() error {
+	if t.tmp == nil {
+		return nil
+	}
+	defer t.discard()
+
+	size, err := t.tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := t.tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     t.name,
+		Size:     size,
+		Mode:     0644,
+		ModTime:  time.Now(),
+	}
+	if err := t.tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(t.tw, t.tmp)
+	return err
+}
+
+// close adds the last file to the tar file and finishes it
+func (t *tarArchive) close() error {
+	if err := t.flush(); err != nil {
+		return err
+	}
+	return t.tw.Close()
+}
+
+// discard removes the temporary file of the file currently being written, if any
+func (t *tarArchive) discard() {
+	if t.tmp == nil {
+		return
+	}
+	t.tmp.Close()
+	os.Remove(t.tmp.Name())
+	t.tmp = nil
+}
@@ -0,0 +1,18 @@
+package snapshot
+
+import (
+	"runtime/metrics"
+	"runtime/pprof"
+)
+
+// numThreads returns the number of OS threads that the runtime currently has. Versions of go that don't report this
+// fall back to the number of threads created over the lifetime of the process, which only differs if threads have
+// exited, as the runtime will rarely do so.
+func numThreads() int {
+	sample := []metrics.Sample{{Name: "/sched/threads/total:threads"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() == metrics.KindUint64 {
+		return int(sample[0].Value.Uint64())
+	}
+	return pprof.Lookup("threadcreate").Count()
+}
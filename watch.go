@@ -0,0 +1,59 @@
+package snapshot
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// WatchGoroutines will check the number of goroutines every interval in the background, and call onExceed with the
+// count once it goes above threshold. onExceed is not called again until the count has dropped below 90% of threshold
+// and then exceeds it again, so that a count oscillating around the threshold doesn't call it repeatedly. Call the
+// returned stop function to stop watching, it is safe to call more than once.
+//
+// onExceed is called from the watching goroutine, the number of goroutines is not checked again until it returns.
+// Checking the number of goroutines is cheap and does not pause execution. If interval is not positive, nothing is
+// watched and ErrInvalidInterval is returned.
+//
+// Example, to capture a snapshot when goroutines appear to be leaking:
+//
+//	stop, err := snapshot.WatchGoroutines(10000, time.Minute, func(count int) {
+//		snapshot.Full(filepath.Join("/var/tmp", snapshot.DefaultFileName()))
+//	})
+//	defer stop()
+func WatchGoroutines(threshold int, interval time.Duration, onExceed func(count int)) (stop func(), err error) {
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	done := make(chan struct{})
+	rearm := threshold - threshold/10
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		exceeded := false
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				count := runtime.NumGoroutine()
+				if !exceeded && count > threshold {
+					exceeded = true
+					onExceed(count)
+				} else if exceeded && count < rearm {
+					exceeded = false
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+		})
+	}, nil
+}
@@ -0,0 +1,45 @@
+package snapshot
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWatchGoroutines(t *testing.T) {
+	exceeded := make(chan int, 1)
+	stop, err := WatchGoroutines(runtime.NumGoroutine(), time.Millisecond, func(count int) {
+		exceeded <- count
+	})
+	if err != nil {
+		t.Fatalf("Error watching goroutines: %s", err.Error())
+	}
+	defer stop()
+	startBlockedWorkers(t, stackClusterSize)
+
+	select {
+	case count := <-exceeded:
+		if count < stackClusterSize {
+			t.Errorf("Unexpected count %d", count)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("onExceed was not called")
+	}
+	stop()
+	stop()
+}
+
+func TestWatchGoroutinesInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		stop, err := WatchGoroutines(1, interval, func(int) {
+			t.Errorf("onExceed called with an invalid interval")
+		})
+		if !errors.Is(err, ErrInvalidInterval) {
+			t.Errorf("Expected ErrInvalidInterval for %s, got %v", interval, err)
+		}
+		if stop != nil {
+			t.Errorf("Expected no stop function for %s", interval)
+		}
+	}
+}